@@ -1,15 +1,20 @@
 package ao3client
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/andybalholm/cascadia"
 	buildinfo "github.com/legowerewolf/AO3fetch/buildinfo"
+	"github.com/legowerewolf/AO3fetch/scheduler"
 	"golang.org/x/net/html"
 )
 
@@ -18,19 +23,53 @@ type Ao3Client struct {
 	userAgentString   string
 	baseUrl           *url.URL
 	authenticatedUser string
+	scheduler         *scheduler.Scheduler
+	proxy             func(*http.Request) (*url.URL, error)
+	cache             *cache // nil if pages aren't cached
+	recorder          *Recorder
+	replayer          *Replayer
+	profile           *Profile
+	hosts             *hosts
+}
+
+// Options holds the settings for an Ao3Client. Zero timeouts mean no limit.
+type Options struct {
+	Scheduler *scheduler.Scheduler // spaces out requests; a default one is used if nil
+	Profile   *Profile             // reads the site's pages; the built-in one is used if nil
+	UserAgent string               // sent with every request; AO3Fetch's own if empty
+
+	ConnectTimeout        time.Duration // to establish a connection
+	ResponseHeaderTimeout time.Duration // from sending a request to receiving the response headers
+	Timeout               time.Duration // for a whole request, including reading the body
+
+	Proxy ProxyConfig
+	Cache CacheConfig
+
+	CanonicalHost string        // host that URLs are built on, whichever host is crawled; the crawled one if empty
+	Mirrors       []string      // hosts to fail over to, in order
+	FailoverAfter time.Duration // how long the crawled host fails before failing over; 0 never does
+
+	Record *Recorder // records every request made
+	Replay *Replayer // answers every request from a recording instead of the site
 }
 
 const loginRoute string = "/users/login"
-const loginField string = "user[login]"
-const passwordField string = "user[password]"
 
-func NewAo3Client(baseUrl string) (*Ao3Client, error) {
+// NewAo3Client creates a client for the site at baseUrl. Every request it makes,
+// including redirects, waits its turn with the options' scheduler, and is sent
+// to baseUrl's host, or a mirror after failing over.
+func NewAo3Client(baseUrl string, options Options) (*Ao3Client, error) {
 	uBaseUrl, err := url.Parse(baseUrl)
 	if err != nil {
 		return nil, err
 	}
 
 	uBaseUrl2 := &url.URL{Scheme: uBaseUrl.Scheme, Host: uBaseUrl.Host}
+	if options.CanonicalHost != "" {
+		uBaseUrl2.Host = options.CanonicalHost
+	}
+
+	hosts := newHosts(uBaseUrl.Host, options.Mirrors, options.FailoverAfter)
 
 	jar, err := cookiejar.New(nil)
 	if err != nil {
@@ -42,10 +81,34 @@ func NewAo3Client(baseUrl string) (*Ao3Client, error) {
 		return nil, err
 	}
 
-	uaString := fmt.Sprintf("AO3Fetch/%s (+https://github.com/legowerewolf/AO3fetch)", (*buildInfo)["vcs.revision.withModified"])
+	uaString := options.UserAgent
+	if uaString == "" {
+		uaString = fmt.Sprintf("AO3Fetch/%s (+https://github.com/legowerewolf/AO3fetch)", (*buildInfo)["vcs.revision.withModified"])
+	}
+
+	sched := options.Scheduler
+	if sched == nil {
+		sched = scheduler.New(scheduler.Config{})
+	}
+
+	proxy, err := options.Proxy.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.OnProxyConnectResponse = proxyConnectError
+	transport.DialContext = (&net.Dialer{Timeout: options.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	if options.ConnectTimeout > 0 {
+		transport.TLSHandshakeTimeout = options.ConnectTimeout
+	}
+	transport.ResponseHeaderTimeout = options.ResponseHeaderTimeout
 
 	client := &http.Client{
-		Jar: jar,
+		Jar:       jar,
+		Transport: transport,
+		Timeout:   options.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// based on default CheckRedirect function
 			if len(via) >= 10 {
@@ -54,20 +117,94 @@ func NewAo3Client(baseUrl string) (*Ao3Client, error) {
 
 			req.Header.Set("User-Agent", uaString)
 
-			return nil
+			if req.URL.Host == uBaseUrl2.Host || slices.Contains(hosts.all, req.URL.Host) {
+				req.URL.Host = hosts.current()
+			}
+
+			return sched.Wait(req.Context())
 		},
 	}
 
-	return &Ao3Client{client: client, userAgentString: uaString, baseUrl: uBaseUrl2}, nil
+	profile := options.Profile
+	if profile == nil {
+		profile = DefaultProfile()
+	}
+
+	ao3Client := &Ao3Client{client: client, userAgentString: uaString, baseUrl: uBaseUrl2, scheduler: sched, proxy: proxy, recorder: options.Record, replayer: options.Replay, profile: profile, hosts: hosts}
+
+	if options.Cache.Dir != "" {
+		if ao3Client.cache, err = newCache(options.Cache); err != nil {
+			return nil, err
+		}
+	}
+
+	return ao3Client, nil
 }
 
+// Do sends a request once the scheduler allows it, unless it can be answered
+// from the cache or a replayed recording. Waiting and the request itself are
+// both cancelled when the request's context is done. Failures at the proxy are
+// returned as a *ProxyError, and pages saying the site is unavailable as an
+// *UnavailableError.
 func (c *Ao3Client) Do(req *http.Request) (*http.Response, error) {
 	req.Header.Set("User-Agent", c.userAgentString)
-	return c.client.Do(req)
+
+	if c.replayer != nil {
+		return c.replayer.replay(req)
+	}
+
+	var resp *http.Response
+	var err error
+	if c.cache != nil && c.cache.handles(req) {
+		resp, err = c.doCached(req)
+	} else {
+		resp, err = c.send(req)
+	}
+
+	// a cancelled request would replay as a failure
+	if c.recorder != nil && !errors.Is(err, context.Canceled) {
+		resp = c.recorder.record(req, resp, err)
+	}
+
+	return resp, err
+}
+
+func (c *Ao3Client) send(req *http.Request) (*http.Response, error) {
+	if err := c.scheduler.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	routed := c.route(req)
+	resp, err := c.client.Do(routed)
+
+	// a request that failed at the proxy never reached the server
+	if proxyErr := c.proxyError(routed, resp, err); proxyErr != nil {
+		return nil, proxyErr
+	}
+
+	if err == nil {
+		resp, err = c.checkBody(resp)
+	}
+
+	var unavailable *UnavailableError
+	if errors.As(err, &unavailable) {
+		c.scheduler.Pause(unavailable.Wait)
+	}
+
+	// a cancelled request says nothing about the server
+	if !errors.Is(err, context.Canceled) {
+		c.scheduler.Observe(resp, err)
+
+		if to, ok := c.hosts.observe(routed.URL.Host, failed(resp, err), c.scheduler.Now()); ok {
+			c.failOver(routed.URL.Host, to)
+		}
+	}
+
+	return resp, err
 }
 
-func (c *Ao3Client) Get(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (c *Ao3Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -75,8 +212,8 @@ func (c *Ao3Client) Get(url string) (*http.Response, error) {
 	return c.Do(req)
 }
 
-func (c *Ao3Client) PostForm(url string, data url.Values) (*http.Response, error) {
-	req, err := http.NewRequest("POST", url, strings.NewReader(data.Encode()))
+func (c *Ao3Client) PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -86,9 +223,9 @@ func (c *Ao3Client) PostForm(url string, data url.Values) (*http.Response, error
 	return c.Do(req)
 }
 
-func (c *Ao3Client) Authenticate(username, password string) error {
+func (c *Ao3Client) Authenticate(ctx context.Context, username, password string) error {
 	// phase 1: get login form
-	getFormResp, apiErr := c.Get(c.baseUrl.JoinPath(loginRoute).String())
+	getFormResp, apiErr := c.Get(ctx, c.baseUrl.JoinPath(loginRoute).String())
 	if apiErr != nil {
 		return fmt.Errorf("Form request failed: error %v", apiErr)
 	}
@@ -105,7 +242,7 @@ func (c *Ao3Client) Authenticate(username, password string) error {
 		return fmt.Errorf("Form parse failed: %w", err)
 	}
 
-	formInputs := cascadia.QueryAll(dom, cascadia.MustCompile("#loginform input"))
+	formInputs := cascadia.QueryAll(dom, c.profile.Login.Inputs)
 
 	formValues := url.Values{}
 
@@ -120,24 +257,24 @@ func (c *Ao3Client) Authenticate(username, password string) error {
 		formValues.Set(n, v)
 	}
 
-	if !formValues.Has(loginField) {
+	if !formValues.Has(c.profile.Login.UsernameField) {
 		return errors.New("Form parse failed: missing username input")
 	}
-	if !formValues.Has(passwordField) {
+	if !formValues.Has(c.profile.Login.PasswordField) {
 		return errors.New("Form parse failed: missing password input")
 	}
 
 	// phase 2: fill data
-	formValues.Set(loginField, username)
-	formValues.Set(passwordField, password)
+	formValues.Set(c.profile.Login.UsernameField, username)
+	formValues.Set(c.profile.Login.PasswordField, password)
 
 	// phase 3: submit
-	_, err = c.PostForm(c.baseUrl.JoinPath(loginRoute).String(), formValues)
+	_, err = c.PostForm(ctx, c.baseUrl.JoinPath(loginRoute).String(), formValues)
 	if err != nil {
 		return err
 	}
 
-	for _, cookie := range c.client.Jar.Cookies(c.baseUrl) {
+	for _, cookie := range c.client.Jar.Cookies(&url.URL{Scheme: c.baseUrl.Scheme, Host: c.hosts.current()}) {
 		if cookie.Name == "user_credentials" {
 			c.authenticatedUser = username
 			return nil
@@ -153,6 +290,16 @@ func (c *Ao3Client) ToFullURL(_url string) string {
 	return c.baseUrl.JoinPath(o.Path).String()
 }
 
+// Profile returns the profile this client reads pages with.
+func (c *Ao3Client) Profile() *Profile {
+	return c.profile
+}
+
+// Scheduler returns the scheduler that spaces out this client's requests.
+func (c *Ao3Client) Scheduler() *scheduler.Scheduler {
+	return c.scheduler
+}
+
 func (c *Ao3Client) GetUser() string {
 	if c.authenticatedUser == "" {
 		return "Anonymous"
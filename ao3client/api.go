@@ -0,0 +1,213 @@
+package ao3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+var ErrNotFound = errors.New("Page not found")
+var ErrRestricted = errors.New("Page is restricted to logged-in users")
+var ErrUnexpectedMarkup = errors.New("Page markup not recognized")
+
+// GetWork fetches a work's full metadata from its page.
+func (c *Ao3Client) GetWork(ctx context.Context, id int) (*Work, error) {
+	workURL := c.baseUrl.JoinPath("/works", strconv.Itoa(id))
+
+	dom, err := c.getDocument(ctx, withViewAdult(workURL).String())
+	if err != nil {
+		return nil, err
+	}
+
+	work, ok := c.ParseWork(dom, workURL.String())
+	if !ok {
+		return nil, ErrUnexpectedMarkup
+	}
+
+	return &work, nil
+}
+
+// GetSeries fetches a series' metadata and every work in it, following the
+// series' pagination.
+func (c *Ao3Client) GetSeries(ctx context.Context, id int) (*Series, error) {
+	seriesURL := c.baseUrl.JoinPath("/series", strconv.Itoa(id)).String()
+
+	dom, err := c.getDocument(ctx, seriesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	series, ok := c.parseSeries(dom, seriesURL)
+	if !ok {
+		return nil, ErrUnexpectedMarkup
+	}
+
+	for work, err := range listFrom(ctx, c, dom, c.profile.Listing.WorkBlurb, c.ParseWorkBlurb) {
+		if err != nil {
+			return nil, err
+		}
+
+		series.Works = append(series.Works, work)
+	}
+
+	return &series, nil
+}
+
+// GetUserProfile fetches a user's public profile.
+func (c *Ao3Client) GetUserProfile(ctx context.Context, name string) (*UserProfile, error) {
+	dom, err := c.getDocument(ctx, c.baseUrl.JoinPath("/users", name, "profile").String())
+	if err != nil {
+		return nil, err
+	}
+
+	profile, ok := c.parseUserProfile(dom, name)
+	if !ok {
+		return nil, ErrUnexpectedMarkup
+	}
+
+	return &profile, nil
+}
+
+// ListWorks iterates over every work blurb on a works listing, such as a tag's
+// works, a search, or a collection's works, following its pagination.
+func (c *Ao3Client) ListWorks(ctx context.Context, listingURL string) iter.Seq2[WorkBlurb, error] {
+	return list(ctx, c, listingURL, c.profile.Listing.WorkBlurb, c.ParseWorkBlurb)
+}
+
+// ListUserWorks iterates over every work posted by a user.
+func (c *Ao3Client) ListUserWorks(ctx context.Context, name string) iter.Seq2[WorkBlurb, error] {
+	return c.ListWorks(ctx, c.baseUrl.JoinPath("/users", name, "works").String())
+}
+
+// ListBookmarks iterates over every bookmark on a bookmarks listing, following
+// its pagination.
+func (c *Ao3Client) ListBookmarks(ctx context.Context, listingURL string) iter.Seq2[Bookmark, error] {
+	return list(ctx, c, listingURL, c.profile.Listing.BookmarkBlurb, c.ParseBookmarkBlurb)
+}
+
+// ListUserBookmarks iterates over every bookmark made by a user. Private
+// bookmarks are included only when authenticated as that user.
+func (c *Ao3Client) ListUserBookmarks(ctx context.Context, name string) iter.Seq2[Bookmark, error] {
+	return c.ListBookmarks(ctx, c.baseUrl.JoinPath("/users", name, "bookmarks").String())
+}
+
+// ListCollections iterates over every collection on a collections listing,
+// following its pagination.
+func (c *Ao3Client) ListCollections(ctx context.Context, listingURL string) iter.Seq2[Collection, error] {
+	return list(ctx, c, listingURL, c.profile.Listing.CollectionBlurb, c.ParseCollectionBlurb)
+}
+
+// ListUserCollections iterates over every collection a user maintains.
+func (c *Ao3Client) ListUserCollections(ctx context.Context, name string) iter.Seq2[Collection, error] {
+	return c.ListCollections(ctx, c.baseUrl.JoinPath("/users", name, "collections").String())
+}
+
+func list[T any](ctx context.Context, c *Ao3Client, listingURL string, selector cascadia.Matcher, parse func(*html.Node) (T, bool)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		dom, err := c.getDocument(ctx, listingURL)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		for item, err := range listFrom(ctx, c, dom, selector, parse) {
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+}
+
+// listFrom yields the items on an already-fetched listing page, then fetches
+// and yields the items on each following page.
+func listFrom[T any](ctx context.Context, c *Ao3Client, dom *html.Node, selector cascadia.Matcher, parse func(*html.Node) (T, bool)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			for _, node := range cascadia.QueryAll(dom, selector) {
+				if item, ok := parse(node); ok {
+					if !yield(item, nil) {
+						return
+					}
+				}
+			}
+
+			next := cascadia.Query(dom, c.profile.Listing.NextPage)
+			if next == nil {
+				return
+			}
+
+			href, _ := getAttr(next, "href")
+			nextURL, err := c.resolve(href)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			dom, err = c.getDocument(ctx, nextURL)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+		}
+	}
+}
+
+// getDocument fetches and parses a page, translating AO3's error responses
+// into errors.
+func (c *Ao3Client) getDocument(ctx context.Context, pageURL string) (*html.Node, error) {
+	resp, err := c.Get(ctx, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("Request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("cf-mitigated") == "challenge" {
+		return nil, errors.New("Encountered Cloudflare challenge; unable to proceed")
+	}
+
+	switch {
+	case resp.StatusCode == 404:
+		return nil, ErrNotFound
+	case resp.StatusCode != 200:
+		return nil, fmt.Errorf("Request failed: invalid status %d / %s", resp.StatusCode, resp.Status)
+	case resp.Request.URL.Path == loginRoute:
+		return nil, ErrRestricted
+	}
+
+	dom, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Page parse failed: %w", err)
+	}
+
+	return dom, nil
+}
+
+// resolve turns an href found on a page into an absolute URL on this client's
+// host, keeping its query.
+func (c *Ao3Client) resolve(href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+
+	return c.baseUrl.ResolveReference(u).String(), nil
+}
+
+func withViewAdult(u *url.URL) *url.URL {
+	query := u.Query()
+	query.Set("view_adult", "true")
+
+	withQuery := *u
+	withQuery.RawQuery = query.Encode()
+
+	return &withQuery
+}
@@ -0,0 +1,240 @@
+package ao3client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/atomicfile"
+)
+
+// region consts
+
+var ErrNotCached = errors.New("Page not in cache, and only the cache may be used")
+
+// Routes that cached pages are aged by.
+const (
+	RouteWork    = "work"
+	RouteSeries  = "series"
+	RouteProfile = "profile"
+	RouteListing = "listing" // anything else: tag works, searches, bookmarks, and so on
+)
+
+var CacheRoutes = []string{RouteWork, RouteSeries, RouteProfile, RouteListing}
+
+// DefaultCacheMaxAge is how long pages on each route are used from the cache
+// before being revalidated. Listings change the most, as works are posted and
+// updated.
+var DefaultCacheMaxAge = map[string]time.Duration{
+	RouteWork:    24 * time.Hour,
+	RouteSeries:  6 * time.Hour,
+	RouteProfile: 24 * time.Hour,
+	RouteListing: time.Hour,
+}
+
+var workRouteMatcher = regexp.MustCompile(`^/works/\d+(/chapters/\d+)?$`)
+var seriesRouteMatcher = regexp.MustCompile(`^/series/\d+$`)
+var profileRouteMatcher = regexp.MustCompile(`^/users/[^/]+/profile$`)
+
+// region cache
+
+// CacheConfig sets up the disk cache. Pages are cached per logged-in user, so
+// restricted pages are never served to a client that isn't logged in.
+type CacheConfig struct {
+	Dir    string                   // where to keep cached pages; no cache is used if empty
+	MaxAge map[string]time.Duration // by route, falling back to DefaultCacheMaxAge
+	Only   bool                     // serve every page from the cache, however old, and never use the network
+}
+
+type cache struct {
+	dir    string
+	maxAge map[string]time.Duration
+	only   bool
+}
+
+type cacheEntry struct {
+	FinalURL string      `json:"finalUrl"` // after redirects
+	Header   http.Header `json:"header"`
+	Stored   time.Time   `json:"stored"`
+}
+
+func newCache(config CacheConfig) (*cache, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	maxAge := maps.Clone(DefaultCacheMaxAge)
+	for route, age := range config.MaxAge {
+		if _, ok := DefaultCacheMaxAge[route]; !ok {
+			return nil, fmt.Errorf("Unknown cache route %q", route)
+		}
+
+		maxAge[route] = age
+	}
+
+	return &cache{dir: config.Dir, maxAge: maxAge, only: config.Only}, nil
+}
+
+// CacheRoute returns the route a page is aged by in the cache.
+func CacheRoute(path string) string {
+	switch {
+	case workRouteMatcher.MatchString(path):
+		return RouteWork
+	case seriesRouteMatcher.MatchString(path):
+		return RouteSeries
+	case profileRouteMatcher.MatchString(path):
+		return RouteProfile
+	default:
+		return RouteListing
+	}
+}
+
+// handles reports whether a request may be answered from the cache. The login
+// form carries a fresh token every time, so it never is.
+func (c *cache) handles(req *http.Request) bool {
+	return req.Method == http.MethodGet && req.URL.Path != loginRoute
+}
+
+func (c *cache) key(req *http.Request, user string) string {
+	sum := sha256.Sum256([]byte(user + " " + req.URL.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *cache) fresh(req *http.Request, entry cacheEntry) bool {
+	return time.Since(entry.Stored) < c.maxAge[CacheRoute(req.URL.Path)]
+}
+
+func (c *cache) load(key string) (entry cacheEntry, body []byte, found bool) {
+	contents, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil || json.Unmarshal(contents, &entry) != nil {
+		return entry, nil, false
+	}
+
+	body, err = os.ReadFile(filepath.Join(c.dir, key+".html"))
+	if err != nil {
+		return entry, nil, false
+	}
+
+	if entry.Header == nil {
+		entry.Header = http.Header{}
+	}
+
+	return entry, body, true
+}
+
+// store saves a page. The body is written first, so a page is never found with
+// only part of it saved.
+func (c *cache) store(key string, entry cacheEntry, body []byte) error {
+	contents, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		if err := atomicfile.WriteFile(filepath.Join(c.dir, key+".html"), body); err != nil {
+			return err
+		}
+	}
+
+	return atomicfile.WriteFile(filepath.Join(c.dir, key+".json"), contents)
+}
+
+// revalidate asks the server to only send the page if it's changed since it
+// was cached.
+func (e cacheEntry) revalidate(req *http.Request) {
+	if etag := e.Header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+}
+
+// response rebuilds the cached response to req.
+func (e cacheEntry) response(req *http.Request, body []byte) *http.Response {
+	finalReq := req.Clone(req.Context())
+	if finalURL, err := url.Parse(e.FinalURL); err == nil {
+		finalReq.URL = finalURL
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       finalReq,
+	}
+}
+
+// region client
+
+// doCached answers a request from the cache if it can, and otherwise sends it,
+// revalidating any stale copy and caching what comes back.
+func (c *Ao3Client) doCached(req *http.Request) (*http.Response, error) {
+	key := c.cache.key(req, c.authenticatedUser)
+	entry, body, found := c.cache.load(key)
+
+	if found && (c.cache.only || c.cache.fresh(req, entry)) {
+		return entry.response(req, body), nil
+	}
+
+	if c.cache.only {
+		return nil, ErrNotCached
+	}
+
+	if found {
+		entry.revalidate(req)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if found && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+
+		for _, header := range []string{"ETag", "Last-Modified"} {
+			if value := resp.Header.Get(header); value != "" {
+				entry.Header.Set(header, value)
+			}
+		}
+		entry.Stored = time.Now()
+
+		// a page that can't be re-stamped is just revalidated again next time
+		c.cache.store(key, entry, nil)
+
+		return entry.response(req, body), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// failing to cache a page shouldn't fail the request for it
+	c.cache.store(key, cacheEntry{FinalURL: resp.Request.URL.String(), Header: resp.Header.Clone(), Stored: time.Now()}, body)
+
+	return resp, nil
+}
@@ -0,0 +1,101 @@
+package ao3client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/scheduler"
+)
+
+func TestCacheRevalidation(t *testing.T) {
+	fetches, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		fetches++
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, "<html>page</html>")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	newClient := func(cache CacheConfig) *Ao3Client {
+		cache.Dir = dir
+		client, err := NewAo3Client(server.URL, Options{Scheduler: scheduler.New(scheduler.Config{Burst: 10}), Cache: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return client
+	}
+
+	get := func(client *Ao3Client, path string) (string, error) {
+		resp, err := client.Get(context.Background(), server.URL+path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	client := newClient(CacheConfig{})
+	for range 2 {
+		if body, err := get(client, "/tags/x/works"); err != nil || body != "<html>page</html>" {
+			t.Fatalf("unexpected page %q (%v)", body, err)
+		}
+	}
+
+	if fetches != 1 || notModified != 0 {
+		t.Errorf("expected a fresh page to be served from the cache, got %d fetches and %d revalidations", fetches, notModified)
+	}
+
+	stale := newClient(CacheConfig{MaxAge: map[string]time.Duration{RouteListing: 0}})
+	if body, err := get(stale, "/tags/x/works"); err != nil || body != "<html>page</html>" {
+		t.Fatalf("unexpected revalidated page %q (%v)", body, err)
+	}
+
+	if fetches != 1 || notModified != 1 {
+		t.Errorf("expected a stale page to be revalidated, got %d fetches and %d revalidations", fetches, notModified)
+	}
+
+	only := newClient(CacheConfig{MaxAge: map[string]time.Duration{RouteListing: 0}, Only: true})
+	if _, err := get(only, "/tags/x/works"); err != nil {
+		t.Errorf("expected a stale page to be served in cache-only mode, got %v", err)
+	}
+
+	if _, err := get(only, "/tags/y/works"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected an uncached page to fail in cache-only mode, got %v", err)
+	}
+
+	if fetches != 1 || notModified != 1 {
+		t.Errorf("expected no requests in cache-only mode, got %d fetches and %d revalidations", fetches, notModified)
+	}
+}
+
+func TestCacheRoute(t *testing.T) {
+	routes := map[string]string{
+		"/works/123":              RouteWork,
+		"/works/123/chapters/456": RouteWork,
+		"/series/7":               RouteSeries,
+		"/users/someone/profile":  RouteProfile,
+		"/tags/x/works":           RouteListing,
+		"/works":                  RouteListing,
+	}
+
+	for path, expected := range routes {
+		if got := CacheRoute(path); got != expected {
+			t.Errorf("%s: expected route %s, got %s", path, expected, got)
+		}
+	}
+}
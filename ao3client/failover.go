@@ -0,0 +1,115 @@
+package ao3client
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// region hosts
+
+// hosts is where requests are sent: the first host, until it's been failing for
+// long enough, then each mirror in turn.
+type hosts struct {
+	mu           sync.Mutex
+	all          []string
+	active       int
+	after        time.Duration // how long failures go on before failing over; 0 never does
+	failingSince time.Time
+}
+
+func newHosts(primary string, mirrors []string, after time.Duration) *hosts {
+	all := []string{primary}
+	for _, mirror := range mirrors {
+		if !slices.ContainsFunc(all, func(h string) bool { return strings.EqualFold(h, mirror) }) {
+			all = append(all, mirror)
+		}
+	}
+
+	return &hosts{all: all, after: after}
+}
+
+func (h *hosts) current() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.all[h.active]
+}
+
+// observe notes whether a request to host failed, and fails over to the next
+// host once the current one has been failing for long enough. It returns the
+// host failed over to, if it did.
+func (h *hosts) observe(host string, failed bool, now time.Time) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// a request that was sent before failing over says nothing about the new host
+	if host != h.all[h.active] {
+		return "", false
+	}
+
+	if !failed {
+		h.failingSince = time.Time{}
+		return "", false
+	}
+
+	if h.failingSince.IsZero() {
+		h.failingSince = now
+	}
+
+	if h.after <= 0 || now.Sub(h.failingSince) < h.after || h.active == len(h.all)-1 {
+		return "", false
+	}
+
+	h.active++
+	h.failingSince = time.Time{}
+
+	return h.all[h.active], true
+}
+
+// region client
+
+// failed reports whether a response shows the host is down rather than just
+// refusing one page. Being rate limited isn't failing: switching hosts to get
+// around it would be rude.
+func failed(resp *http.Response, err error) bool {
+	var unavailable *UnavailableError
+	if errors.As(err, &unavailable) {
+		return unavailable.Reason != reasonRetryLater
+	}
+
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// route points a request for any of the site's hosts at the one currently in
+// use, so that URLs can be built on the canonical host whichever is crawled.
+func (c *Ao3Client) route(req *http.Request) *http.Request {
+	if req.URL.Host != c.baseUrl.Host && !slices.Contains(c.hosts.all, req.URL.Host) {
+		return req
+	}
+
+	routed := req.Clone(req.Context())
+	routed.URL.Host = c.hosts.current()
+	routed.Host = ""
+
+	return routed
+}
+
+// failOver copies the session's cookies to the host being failed over to, so
+// that a login is kept if the mirror accepts it.
+func (c *Ao3Client) failOver(from, to string) {
+	fromURL := &url.URL{Scheme: c.baseUrl.Scheme, Host: from, Path: "/"}
+	toURL := &url.URL{Scheme: c.baseUrl.Scheme, Host: to, Path: "/"}
+
+	c.client.Jar.SetCookies(toURL, c.client.Jar.Cookies(fromURL))
+}
+
+// Host returns the host requests are currently sent to. It changes when the
+// site fails over to a mirror.
+func (c *Ao3Client) Host() string {
+	return c.hosts.current()
+}
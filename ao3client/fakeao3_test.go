@@ -0,0 +1,228 @@
+package ao3client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/fakeao3"
+	"github.com/legowerewolf/AO3fetch/scheduler"
+)
+
+func newFakeSiteClient(t *testing.T, site *fakeao3.Server) (*Ao3Client, *fakeao3.Clock) {
+	clock := fakeao3.NewClock()
+
+	client, err := NewAo3Client(site.URL, Options{Scheduler: scheduler.New(scheduler.Config{Clock: clock})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, clock
+}
+
+func TestFakeSiteListing(t *testing.T) {
+	site := fakeao3.New()
+	defer site.Close()
+
+	for id := 1; id <= 45; id++ {
+		site.AddWork(fakeao3.Work{ID: id, Title: "Work " + strconv.Itoa(id), Author: "writer", Tags: []string{"Tag"}})
+	}
+	site.AddSeries(fakeao3.Series{ID: 1, Title: "A Series", Author: "writer", Works: []int{2, 1}})
+
+	client, _ := newFakeSiteClient(t, site)
+
+	count := 0
+	for work, err := range client.ListWorks(context.Background(), site.URL+"/tags/Tag/works") {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count++
+		if work.ID == 2 && (len(work.Series) != 1 || work.Series[0].Position != 1) {
+			t.Errorf("expected work 2 to be first in its series, got %v", work.Series)
+		}
+	}
+
+	if count != 45 {
+		t.Errorf("expected 45 works across three pages, got %d", count)
+	}
+
+	series, err := client.GetSeries(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if series.Title != "A Series" || len(series.Works) != 2 || series.Works[0].ID != 2 {
+		t.Errorf("unexpected series %+v", series)
+	}
+}
+
+func TestFakeSiteLogin(t *testing.T) {
+	site := fakeao3.New()
+	defer site.Close()
+
+	site.AddUser("reader", "hunter2")
+	site.AddWork(fakeao3.Work{ID: 1, Title: "Locked", Author: "writer", Restricted: true})
+
+	client, _ := newFakeSiteClient(t, site)
+
+	if _, err := client.GetWork(context.Background(), 1); !errors.Is(err, ErrRestricted) {
+		t.Errorf("expected a restricted work to need a login, got %v", err)
+	}
+
+	if err := client.Authenticate(context.Background(), "reader", "wrong"); err == nil {
+		t.Error("expected a wrong password to fail")
+	}
+
+	if err := client.Authenticate(context.Background(), "reader", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if client.GetUser() != "reader" {
+		t.Errorf("expected to be logged in as reader, got %s", client.GetUser())
+	}
+
+	if work, err := client.GetWork(context.Background(), 1); err != nil || !work.Restricted {
+		t.Errorf("expected to read the restricted work once logged in, got %+v (%v)", work, err)
+	}
+}
+
+func TestFakeSiteFaults(t *testing.T) {
+	site := fakeao3.New()
+	defer site.Close()
+
+	site.AddWork(fakeao3.Work{ID: 1, Title: "Work", Author: "writer"})
+	site.Fail("/works/1", fakeao3.RetryAfter(120), fakeao3.ServerError(503), fakeao3.Challenge())
+
+	client, clock := newFakeSiteClient(t, site)
+	sched := client.Scheduler()
+
+	if _, err := client.GetWork(context.Background(), 1); err == nil {
+		t.Error("expected a rate-limited request to fail")
+	}
+
+	if next := sched.Next(); next.Sub(clock.Now()) < 2*time.Minute {
+		t.Errorf("expected requests to pause for the Retry-After time, next is in %s", next.Sub(clock.Now()))
+	}
+
+	delay := sched.Delay()
+	if _, err := client.GetWork(context.Background(), 1); err == nil {
+		t.Error("expected a server error to fail")
+	}
+
+	if sched.Delay() <= delay {
+		t.Error("expected a server error to back off")
+	}
+
+	delay = sched.Delay()
+	if _, err := client.GetWork(context.Background(), 1); err == nil {
+		t.Error("expected a Cloudflare challenge to fail")
+	}
+
+	if sched.Delay() <= delay {
+		t.Error("expected a Cloudflare challenge to back off")
+	}
+
+	if work, err := client.GetWork(context.Background(), 1); err != nil || work.Title != "Work" {
+		t.Errorf("expected the work once the faults were served, got %+v (%v)", work, err)
+	}
+}
+
+func TestFakeSiteFailover(t *testing.T) {
+	primary := fakeao3.New()
+	defer primary.Close()
+	mirror := fakeao3.New()
+	defer mirror.Close()
+
+	for _, site := range []*fakeao3.Server{primary, mirror} {
+		site.AddUser("reader", "hunter2")
+		site.AddWork(fakeao3.Work{ID: 1, Title: "Work", Author: "writer"})
+	}
+
+	mirrorURL, _ := url.Parse(mirror.URL)
+	clock := fakeao3.NewClock()
+	client, err := NewAo3Client(primary.URL, Options{
+		Scheduler:     scheduler.New(scheduler.Config{Clock: clock}),
+		CanonicalHost: "archiveofourown.org",
+		Mirrors:       []string{mirrorURL.Host},
+		FailoverAfter: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Authenticate(context.Background(), "reader", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// being rate limited, however long for, is no reason to switch hosts
+	for range 10 {
+		primary.Fail("/works/1", fakeao3.RetryLaterPage())
+	}
+	for range 10 {
+		client.GetWork(context.Background(), 1)
+	}
+
+	if client.Host() == mirrorURL.Host {
+		t.Fatal("expected \"Retry later\" pages not to fail over")
+	}
+
+	for range 20 {
+		primary.Fail("/works/1", fakeao3.ServerError(503))
+	}
+
+	var work *Work
+	for range 20 {
+		if work, err = client.GetWork(context.Background(), 1); err == nil {
+			break
+		}
+	}
+
+	if work == nil || client.Host() != mirrorURL.Host {
+		t.Fatalf("expected to fail over to the mirror and read the work there, on %s with %v", client.Host(), err)
+	}
+
+	if work.URL != "http://archiveofourown.org/works/1" {
+		t.Errorf("expected the work's URL to be on the canonical host, got %s", work.URL)
+	}
+
+	if !slices.ContainsFunc(client.client.Jar.Cookies(mirrorURL), func(c *http.Cookie) bool { return c.Name == "user_credentials" }) {
+		t.Error("expected the login cookies to be copied to the mirror")
+	}
+}
+
+func TestFakeSiteUnavailablePages(t *testing.T) {
+	site := fakeao3.New()
+	defer site.Close()
+
+	site.AddWork(fakeao3.Work{ID: 1, Title: "Work", Author: "writer"})
+	site.AddWork(fakeao3.Work{ID: 2, Title: "Down for maintenance", Author: "writer"})
+	site.Fail("/works/1", fakeao3.RetryLaterPage(), fakeao3.MaintenancePage())
+
+	client, clock := newFakeSiteClient(t, site)
+	sched := client.Scheduler()
+
+	for _, wait := range []time.Duration{retryLaterWait, maintenanceWait} {
+		var unavailable *UnavailableError
+		if _, err := client.GetWork(context.Background(), 1); !errors.As(err, &unavailable) {
+			t.Fatalf("expected the page to be recognized as the site being unavailable, got %v", err)
+		}
+
+		if next := sched.Next(); next.Sub(clock.Now()) < wait {
+			t.Errorf("%s: expected requests to pause for %s, next is in %s", unavailable.Reason, wait, next.Sub(clock.Now()))
+		}
+	}
+
+	if work, err := client.GetWork(context.Background(), 1); err != nil || work.Title != "Work" {
+		t.Errorf("expected the work once the site was back, got %+v (%v)", work, err)
+	}
+
+	if work, err := client.GetWork(context.Background(), 2); err != nil || work.Title != "Down for maintenance" {
+		t.Errorf("expected a real page mentioning maintenance to be read, got %+v (%v)", work, err)
+	}
+}
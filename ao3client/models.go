@@ -0,0 +1,103 @@
+package ao3client
+
+// Creator is a link to a user or pseud, as shown in bylines.
+type Creator struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// SeriesPosition is a work's membership in a series.
+type SeriesPosition struct {
+	Position int    `json:"position"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+}
+
+// WorkBlurb is the summary of a work shown on listing pages.
+type WorkBlurb struct {
+	ID            int              `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	Authors       []Creator        `json:"authors,omitempty"`
+	Recipients    []Creator        `json:"recipients,omitempty"`
+	Fandoms       []string         `json:"fandoms,omitempty"`
+	Rating        string           `json:"rating,omitempty"`
+	Warnings      []string         `json:"warnings,omitempty"`
+	Categories    []string         `json:"categories,omitempty"`
+	Relationships []string         `json:"relationships,omitempty"`
+	Characters    []string         `json:"characters,omitempty"`
+	Freeforms     []string         `json:"freeforms,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	Series        []SeriesPosition `json:"series,omitempty"`
+	Language      string           `json:"language,omitempty"`
+	Words         int              `json:"words"`
+	Chapters      int              `json:"chapters"`
+	TotalChapters int              `json:"totalChapters"` // 0 when unknown
+	Kudos         int              `json:"kudos"`
+	Hits          int              `json:"hits"`
+	Complete      bool             `json:"complete"`
+	Restricted    bool             `json:"restricted"`
+	Updated       string           `json:"updated,omitempty"`
+}
+
+// Work is a work's full metadata, as shown on its own page.
+type Work struct {
+	WorkBlurb
+
+	Published     string   `json:"published,omitempty"`
+	Comments      int      `json:"comments"`
+	Bookmarks     int      `json:"bookmarks"`
+	Collections   []string `json:"collections,omitempty"`
+	InspiredBy    []string `json:"inspiredBy,omitempty"`
+	Inspired      []string `json:"inspired,omitempty"`
+	TranslationOf []string `json:"translationOf,omitempty"`
+	Translations  []string `json:"translations,omitempty"`
+}
+
+// Series is a series' metadata and the works in it.
+type Series struct {
+	ID          int         `json:"id"`
+	URL         string      `json:"url"`
+	Title       string      `json:"title"`
+	Creators    []Creator   `json:"creators,omitempty"`
+	Begun       string      `json:"begun,omitempty"`
+	Updated     string      `json:"updated,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Notes       string      `json:"notes,omitempty"`
+	Words       int         `json:"words"`
+	Complete    bool        `json:"complete"`
+	Works       []WorkBlurb `json:"works,omitempty"`
+}
+
+// UserProfile is the public profile of a user.
+type UserProfile struct {
+	Name   string    `json:"name"`
+	URL    string    `json:"url"`
+	ID     int       `json:"id"`
+	Joined string    `json:"joined,omitempty"`
+	Pseuds []Creator `json:"pseuds,omitempty"`
+	Bio    string    `json:"bio,omitempty"`
+}
+
+// Bookmark is the bookmarker's own data attached to a bookmark blurb. Work is
+// set when the bookmarked item is a work.
+type Bookmark struct {
+	ID          string     `json:"id,omitempty"`
+	Item        string     `json:"item"`
+	Bookmarker  string     `json:"bookmarker,omitempty"`
+	Date        string     `json:"date,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Collections []string   `json:"collections,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	Rec         bool       `json:"rec"`
+	Private     bool       `json:"private"`
+	Work        *WorkBlurb `json:"work,omitempty"`
+}
+
+// Collection is the summary of a collection shown on listing pages.
+type Collection struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Summary string `json:"summary,omitempty"`
+}
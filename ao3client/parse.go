@@ -0,0 +1,456 @@
+package ao3client
+
+import (
+	"bytes"
+	"iter"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+var numberMatcher = regexp.MustCompile(`\d+`)
+
+// a translation's page names its original, and the original's lists its translations
+var translationOfMatcher = regexp.MustCompile(`(?i)^(a )?translation of\b`)
+var translationIntoMatcher = regexp.MustCompile(`(?i)^translation into .* available`)
+
+var dateLayouts = []string{"02 Jan 2006", time.DateOnly}
+
+// region blurbs
+
+// ParseWorkBlurb extracts a work's summary from a work blurb on a listing
+// page.
+func (c *Ao3Client) ParseWorkBlurb(blurb *html.Node) (w WorkBlurb, ok bool) {
+	title := cascadia.Query(blurb, c.profile.Blurb.Title)
+	if title == nil {
+		return
+	}
+
+	href, _ := getAttr(title, "href")
+	w.URL = c.ToFullURL(href)
+	w.ID = idFromPath(href)
+	w.Title = nodeText(title)
+
+	w.Authors = c.queryCreators(blurb, c.profile.Blurb.Author)
+	w.Recipients = c.queryCreators(blurb, c.profile.Blurb.Recipient)
+	w.Fandoms = queryTexts(blurb, c.profile.Blurb.Fandom)
+
+	if rating := cascadia.Query(blurb, c.profile.Blurb.Rating); rating != nil {
+		w.Rating, _ = getAttr(rating, "title")
+	}
+
+	if category := cascadia.Query(blurb, c.profile.Blurb.Category); category != nil {
+		categories, _ := getAttr(category, "title")
+		w.Categories = splitList(categories)
+	}
+
+	w.Complete = cascadia.Query(blurb, c.profile.Blurb.Complete) != nil
+	w.Restricted = cascadia.Query(blurb, c.profile.Blurb.Restricted) != nil
+
+	if date := cascadia.Query(blurb, c.profile.Blurb.Date); date != nil {
+		w.Updated = normalizeDate(nodeText(date))
+	}
+
+	w.Warnings = queryTexts(blurb, c.profile.Blurb.Warning)
+	w.Relationships = queryTexts(blurb, c.profile.Blurb.Relationship)
+	w.Characters = queryTexts(blurb, c.profile.Blurb.Character)
+	w.Freeforms = queryTexts(blurb, c.profile.Blurb.Freeform)
+
+	if summary := cascadia.Query(blurb, c.profile.Blurb.Summary); summary != nil {
+		w.Summary = innerHTML(summary)
+	}
+
+	for _, series := range cascadia.QueryAll(blurb, c.profile.Blurb.Series) {
+		if position, ok := c.parseSeriesPosition(series); ok {
+			w.Series = append(w.Series, position)
+		}
+	}
+
+	if stats := cascadia.Query(blurb, c.profile.Page.Stats); stats != nil {
+		c.parseStats(stats, &w)
+	}
+
+	return w, true
+}
+
+// ParseBookmarkBlurb extracts the bookmarker's data from a bookmark blurb on a
+// listing page.
+func (c *Ao3Client) ParseBookmarkBlurb(blurb *html.Node) (b Bookmark, ok bool) {
+	item := cascadia.Query(blurb, c.profile.Bookmark.Item)
+	if item == nil {
+		return
+	}
+
+	href, _ := getAttr(item, "href")
+	b.Item = c.ToFullURL(href)
+
+	if id, err := getAttr(blurb, "id"); err == nil {
+		b.ID = strings.TrimPrefix(id, "bookmark_")
+	}
+
+	if work, ok := c.ParseWorkBlurb(blurb); ok {
+		b.Work = &work
+	}
+
+	userModule := cascadia.Query(blurb, c.profile.Bookmark.UserModule)
+	if userModule == nil {
+		return b, true
+	}
+
+	if bookmarker := cascadia.Query(userModule, c.profile.Bookmark.Bookmarker); bookmarker != nil {
+		b.Bookmarker = nodeText(bookmarker)
+	}
+
+	if date := cascadia.Query(userModule, c.profile.Bookmark.Date); date != nil {
+		b.Date = normalizeDate(nodeText(date))
+	}
+
+	b.Tags = queryTexts(userModule, c.profile.Bookmark.Tag)
+	b.Collections = c.queryLinks(userModule, c.profile.Page.CollectionLink)
+
+	if notes := cascadia.Query(userModule, c.profile.Bookmark.Notes); notes != nil {
+		b.Notes = innerHTML(notes)
+	}
+
+	b.Rec = cascadia.Query(userModule, c.profile.Bookmark.Rec) != nil
+	b.Private = cascadia.Query(userModule, c.profile.Bookmark.Private) != nil
+
+	return b, true
+}
+
+// ParseCollectionBlurb extracts a collection's summary from a collection blurb
+// on a listing page.
+func (c *Ao3Client) ParseCollectionBlurb(blurb *html.Node) (col Collection, ok bool) {
+	title := cascadia.Query(blurb, c.profile.Blurb.CollectionTitle)
+	if title == nil {
+		return
+	}
+
+	href, _ := getAttr(title, "href")
+	col.URL = c.ToFullURL(href)
+	col.Name = path.Base(href)
+	col.Title = nodeText(title)
+
+	if summary := cascadia.Query(blurb, c.profile.Blurb.Summary); summary != nil {
+		col.Summary = innerHTML(summary)
+	}
+
+	return col, true
+}
+
+// region pages
+
+// ParseWork extracts a work's full metadata from its page.
+func (c *Ao3Client) ParseWork(dom *html.Node, workURL string) (w Work, ok bool) {
+	title := cascadia.Query(dom, c.profile.Work.Title)
+	if title == nil {
+		return
+	}
+
+	w.URL = workURL
+	w.ID = idFromPath(workURL)
+	w.Title = nodeText(title)
+	w.Restricted = cascadia.Query(dom, c.profile.Work.Restricted) != nil
+
+	w.Authors = c.queryCreators(dom, c.profile.Work.Author)
+	w.Recipients = c.queryCreators(dom, c.profile.Work.Recipient)
+
+	if rating := cascadia.Query(dom, c.profile.Work.Rating); rating != nil {
+		w.Rating = nodeText(rating)
+	}
+
+	w.Warnings = queryTexts(dom, c.profile.Work.Warning)
+	w.Categories = queryTexts(dom, c.profile.Work.Category)
+	w.Fandoms = queryTexts(dom, c.profile.Work.Fandom)
+	w.Relationships = queryTexts(dom, c.profile.Work.Relationship)
+	w.Characters = queryTexts(dom, c.profile.Work.Character)
+	w.Freeforms = queryTexts(dom, c.profile.Work.Freeform)
+	w.Collections = c.queryLinks(dom, c.profile.Work.Collection)
+
+	if language := cascadia.Query(dom, c.profile.Work.Language); language != nil {
+		w.Language = nodeText(language)
+	}
+
+	for _, series := range cascadia.QueryAll(dom, c.profile.Work.Series) {
+		if position, ok := c.parseSeriesPosition(series); ok {
+			w.Series = append(w.Series, position)
+		}
+	}
+
+	if summary := cascadia.Query(dom, c.profile.Work.Summary); summary != nil {
+		w.Summary = innerHTML(summary)
+	}
+
+	for _, association := range cascadia.QueryAll(dom, c.profile.Work.Association) {
+		text := nodeText(association)
+
+		switch {
+		case strings.HasPrefix(text, "Inspired by"):
+			w.InspiredBy = append(w.InspiredBy, c.queryLinks(association, c.profile.Page.WorkLink)...)
+		case translationOfMatcher.MatchString(text):
+			w.TranslationOf = append(w.TranslationOf, c.queryLinks(association, c.profile.Page.WorkLink)...)
+		case translationIntoMatcher.MatchString(text):
+			w.Translations = append(w.Translations, c.queryLinks(association, c.profile.Page.WorkLink)...)
+		}
+	}
+
+	w.Inspired = c.queryLinks(dom, c.profile.Work.Children)
+
+	if stats := cascadia.Query(dom, c.profile.Page.Stats); stats != nil {
+		c.parseStats(stats, &w.WorkBlurb)
+
+		for term, def := range definitions(stats) {
+			switch term {
+			case "published":
+				w.Published = normalizeDate(nodeText(def))
+			case "status":
+				w.Updated = normalizeDate(nodeText(def))
+			case "comments":
+				w.Comments = parseCount(nodeText(def))
+			case "bookmarks":
+				w.Bookmarks = parseCount(nodeText(def))
+			}
+		}
+	}
+
+	if w.Updated == "" {
+		w.Updated = w.Published
+	}
+
+	w.Complete = w.TotalChapters != 0 && w.Chapters == w.TotalChapters
+
+	return w, true
+}
+
+func (c *Ao3Client) parseSeries(dom *html.Node, seriesURL string) (s Series, ok bool) {
+	title := cascadia.Query(dom, c.profile.Page.Heading)
+	meta := cascadia.Query(dom, c.profile.Page.MetaList)
+	if title == nil || meta == nil {
+		return
+	}
+
+	s.URL = seriesURL
+	s.ID = idFromPath(seriesURL)
+	s.Title = nodeText(title)
+
+	for term, def := range definitions(meta) {
+		switch term {
+		case "creator", "creators":
+			s.Creators = c.queryCreators(def, c.profile.Page.AuthorLink)
+		case "series begun":
+			s.Begun = normalizeDate(nodeText(def))
+		case "series updated":
+			s.Updated = normalizeDate(nodeText(def))
+		case "description":
+			if userstuff := cascadia.Query(def, c.profile.Page.Userstuff); userstuff != nil {
+				s.Description = innerHTML(userstuff)
+			}
+		case "notes":
+			if userstuff := cascadia.Query(def, c.profile.Page.Userstuff); userstuff != nil {
+				s.Notes = innerHTML(userstuff)
+			}
+		}
+	}
+
+	if stats := cascadia.Query(meta, c.profile.Page.Stats); stats != nil {
+		for term, def := range definitions(stats) {
+			switch term {
+			case "words":
+				s.Words = parseCount(nodeText(def))
+			case "complete":
+				s.Complete = nodeText(def) == "Yes"
+			}
+		}
+	}
+
+	return s, true
+}
+
+func (c *Ao3Client) parseUserProfile(dom *html.Node, name string) (p UserProfile, ok bool) {
+	meta := cascadia.Query(dom, c.profile.Page.MetaList)
+	if meta == nil {
+		return
+	}
+
+	p.Name = name
+	p.URL = c.baseUrl.JoinPath("/users", name).String()
+
+	for term, def := range definitions(meta) {
+		switch term {
+		case "my pseuds":
+			p.Pseuds = c.queryCreators(def, c.profile.Page.Link)
+		case "i joined on":
+			p.Joined = normalizeDate(nodeText(def))
+		case "my user id is":
+			p.ID = parseCount(nodeText(def))
+		}
+	}
+
+	if bio := cascadia.Query(dom, c.profile.Page.Bio); bio != nil {
+		p.Bio = innerHTML(bio)
+	}
+
+	return p, true
+}
+
+// region helpers
+
+func (c *Ao3Client) parseStats(stats *html.Node, w *WorkBlurb) {
+	for term, def := range definitions(stats) {
+		switch term {
+		case "language":
+			w.Language = nodeText(def)
+		case "words":
+			w.Words = parseCount(nodeText(def))
+		case "chapters":
+			posted, total, _ := strings.Cut(nodeText(def), "/")
+			w.Chapters = parseCount(posted)
+			w.TotalChapters = parseCount(total)
+		case "kudos":
+			w.Kudos = parseCount(nodeText(def))
+		case "hits":
+			w.Hits = parseCount(nodeText(def))
+		}
+	}
+}
+
+func (c *Ao3Client) parseSeriesPosition(n *html.Node) (s SeriesPosition, ok bool) {
+	link := cascadia.Query(n, c.profile.Page.SeriesLink)
+	if link == nil {
+		return
+	}
+
+	href, _ := getAttr(link, "href")
+	s.URL = c.ToFullURL(href)
+	s.Title = nodeText(link)
+	s.Position = parseCount(numberMatcher.FindString(nodeText(n)))
+
+	return s, true
+}
+
+func (c *Ao3Client) queryCreators(n *html.Node, selector cascadia.Matcher) (creators []Creator) {
+	for _, link := range cascadia.QueryAll(n, selector) {
+		href, _ := getAttr(link, "href")
+		creators = append(creators, Creator{Name: nodeText(link), URL: c.ToFullURL(href)})
+	}
+
+	return
+}
+
+func (c *Ao3Client) queryLinks(n *html.Node, selector cascadia.Matcher) (links []string) {
+	for _, link := range cascadia.QueryAll(n, selector) {
+		href, _ := getAttr(link, "href")
+		links = append(links, c.ToFullURL(href))
+	}
+
+	return
+}
+
+func queryTexts(n *html.Node, selector cascadia.Matcher) (texts []string) {
+	for _, match := range cascadia.QueryAll(n, selector) {
+		texts = append(texts, nodeText(match))
+	}
+
+	return
+}
+
+// definitions iterates over the term/definition pairs of a <dl>. Terms are
+// lowercased and stripped of their trailing colon; if a term has a class, the
+// class is used instead.
+func definitions(dl *html.Node) iter.Seq2[string, *html.Node] {
+	return func(yield func(string, *html.Node) bool) {
+		term := ""
+
+		for child := dl.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+
+			switch child.Data {
+			case "dt":
+				if class, err := getAttr(child, "class"); err == nil && class != "" {
+					term = strings.Fields(class)[0]
+				} else {
+					term = strings.ToLower(strings.TrimSuffix(nodeText(child), ":"))
+				}
+			case "dd":
+				if !yield(term, child) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func idFromPath(href string) int {
+	u, err := url.Parse(href)
+	if err != nil {
+		return 0
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 {
+		return 0
+	}
+
+	id, _ := strconv.Atoi(segments[1])
+	return id
+}
+
+func parseCount(s string) int {
+	i, _ := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(s), ",", ""))
+	return i
+}
+
+func splitList(s string) (items []string) {
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return
+}
+
+func normalizeDate(s string) string {
+	for _, layout := range dateLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			return parsed.Format(time.DateOnly)
+		}
+	}
+
+	return s
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func innerHTML(n *html.Node) string {
+	var b bytes.Buffer
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&b, c)
+	}
+
+	return strings.TrimSpace(b.String())
+}
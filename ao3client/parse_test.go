@@ -0,0 +1,501 @@
+package ao3client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/legowerewolf/AO3fetch/fakeao3"
+	"github.com/legowerewolf/AO3fetch/scheduler"
+	"golang.org/x/net/html"
+)
+
+// pageServer serves fixed pages by path and query, and notes every request it
+// gets.
+type pageServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newPageServer(t *testing.T, pages map[string]string) (*pageServer, *Ao3Client) {
+	s := &pageServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.RequestURI())
+		s.mu.Unlock()
+
+		page, ok := pages[r.URL.RequestURI()]
+
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case strings.HasPrefix(page, "redirect:"):
+			http.Redirect(w, r, strings.TrimPrefix(page, "redirect:"), http.StatusFound)
+		default:
+			io.WriteString(w, `<!DOCTYPE html><html><body><div id="main" role="main">`+page+`</div></body></html>`)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	client, err := NewAo3Client(s.URL, Options{Scheduler: scheduler.New(scheduler.Config{Clock: fakeao3.NewClock()})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, client
+}
+
+func (s *pageServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+const workPage = `
+<dl class="work meta group">
+  <dt class="rating tags">Rating:</dt>
+  <dd class="rating tags"><ul class="commas"><li><a class="tag" href="/tags/Teen%20And%20Up%20Audiences/works">Teen And Up Audiences</a></li></ul></dd>
+  <dt class="warning tags">Archive Warning:</dt>
+  <dd class="warning tags"><ul class="commas"><li><a class="tag" href="/tags/No%20Archive%20Warnings%20Apply/works">No Archive Warnings Apply</a></li></ul></dd>
+  <dt class="category tags">Category:</dt>
+  <dd class="category tags"><ul class="commas"><li><a class="tag" href="/tags/Gen/works">Gen</a></li></ul></dd>
+  <dt class="fandom tags">Fandom:</dt>
+  <dd class="fandom tags"><ul class="commas"><li><a class="tag" href="/tags/Sherlock%20(TV)/works">Sherlock (TV)</a></li></ul></dd>
+  <dt class="relationship tags">Relationship:</dt>
+  <dd class="relationship tags"><ul class="commas"><li><a class="tag" href="/tags/John%20Watson%20*a*%20Sherlock%20Holmes/works">John Watson &amp; Sherlock Holmes</a></li></ul></dd>
+  <dt class="character tags">Characters:</dt>
+  <dd class="character tags"><ul class="commas"><li><a class="tag" href="/tags/John%20Watson/works">John Watson</a></li><li><a class="tag" href="/tags/Sherlock%20Holmes/works">Sherlock Holmes</a></li></ul></dd>
+  <dt class="freeform tags">Additional Tags:</dt>
+  <dd class="freeform tags"><ul class="commas"><li><a class="tag" href="/tags/Fluff/works">Fluff</a></li></ul></dd>
+  <dt class="language">Language:</dt>
+  <dd class="language" lang="en">English</dd>
+  <dt class="series">Series:</dt>
+  <dd class="series"><span class="series"><span class="position">Part 2 of <a href="/series/777">Baker Street Oddities</a></span></span></dd>
+  <dt class="collections">Collections:</dt>
+  <dd class="collections"><a href="/collections/fridge_fest_2023">Fridge Fest 2023</a></dd>
+  <dt class="stats">Stats:</dt>
+  <dd class="stats">
+    <dl class="stats">
+      <dt class="published">Published:</dt><dd class="published">2022-12-01</dd>
+      <dt class="status">Updated:</dt><dd class="status">2023-03-12</dd>
+      <dt class="words">Words:</dt><dd class="words">4,321</dd>
+      <dt class="chapters">Chapters:</dt><dd class="chapters">2/?</dd>
+      <dt class="comments">Comments:</dt><dd class="comments">31</dd>
+      <dt class="kudos">Kudos:</dt><dd class="kudos">210</dd>
+      <dt class="bookmarks">Bookmarks:</dt><dd class="bookmarks"><a href="/works/12345/bookmarks">40</a></dd>
+      <dt class="hits">Hits:</dt><dd class="hits">5,678</dd>
+    </dl>
+  </dd>
+</dl>
+<div id="workskin">
+  <div class="preface group">
+    <h2 class="title heading">A Study in Something <img alt="(Restricted)" title="Restricted" src="/images/lockblue.png"></h2>
+    <h3 class="byline heading"><a rel="author" href="/users/writer/pseuds/writer">writer</a></h3>
+    <div class="summary module">
+      <h3 class="heading">Summary:</h3>
+      <blockquote class="userstuff"><p>John finds a <em>very</em> strange thing in the fridge.</p></blockquote>
+    </div>
+    <div class="notes module">
+      <ul class="associations">
+        <li>For <a href="/users/friend/gifts">friend</a>.</li>
+        <li>Inspired by <a href="/works/10000">The Original</a> by <a rel="author" href="/users/elder/pseuds/elder">elder</a>.</li>
+        <li>A translation of <a href="/works/9000">Une Étude</a> by <a rel="author" href="/users/auteur/pseuds/auteur">auteur</a>.</li>
+        <li>Translation into Deutsch available: <a href="/works/30000">Eine Studie</a> by <a rel="author" href="/users/translator/pseuds/translator">translator</a>.</li>
+      </ul>
+    </div>
+  </div>
+</div>
+<div id="children" class="children module">
+  <ul><li><a href="/works/20000">A Fridge Too Far</a> by <a href="/users/younger/pseuds/younger">younger</a></li></ul>
+</div>`
+
+func TestGetWork(t *testing.T) {
+	server, client := newPageServer(t, map[string]string{"/works/12345?view_adult=true": workPage})
+
+	work, err := client.GetWork(context.Background(), 12345)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Work{
+		WorkBlurb: WorkBlurb{
+			ID:            12345,
+			URL:           server.URL + "/works/12345",
+			Title:         "A Study in Something",
+			Authors:       []Creator{{Name: "writer", URL: server.URL + "/users/writer/pseuds/writer"}},
+			Recipients:    []Creator{{Name: "friend", URL: server.URL + "/users/friend/gifts"}},
+			Fandoms:       []string{"Sherlock (TV)"},
+			Rating:        "Teen And Up Audiences",
+			Warnings:      []string{"No Archive Warnings Apply"},
+			Categories:    []string{"Gen"},
+			Relationships: []string{"John Watson & Sherlock Holmes"},
+			Characters:    []string{"John Watson", "Sherlock Holmes"},
+			Freeforms:     []string{"Fluff"},
+			Summary:       "<p>John finds a <em>very</em> strange thing in the fridge.</p>",
+			Series:        []SeriesPosition{{Position: 2, Title: "Baker Street Oddities", URL: server.URL + "/series/777"}},
+			Language:      "English",
+			Words:         4321,
+			Chapters:      2,
+			Kudos:         210,
+			Hits:          5678,
+			Restricted:    true,
+			Updated:       "2023-03-12",
+		},
+		Published:     "2022-12-01",
+		Comments:      31,
+		Bookmarks:     40,
+		Collections:   []string{server.URL + "/collections/fridge_fest_2023"},
+		InspiredBy:    []string{server.URL + "/works/10000"},
+		Inspired:      []string{server.URL + "/works/20000"},
+		TranslationOf: []string{server.URL + "/works/9000"},
+		Translations:  []string{server.URL + "/works/30000"},
+	}
+
+	if !reflect.DeepEqual(work, expected) {
+		t.Errorf("expected %+v, got %+v", expected, work)
+	}
+
+	if requests := server.Requests(); !slices.Equal(requests, []string{"/works/12345?view_adult=true"}) {
+		t.Errorf("expected the work to be fetched past the adult content warning, got %v", requests)
+	}
+}
+
+func TestGetSeries(t *testing.T) {
+	blurb := func(id, part string) string {
+		return `<li id="work_` + id + `" class="work blurb group" role="article">
+  <div class="header module"><h4 class="heading"><a href="/works/` + id + `">Part ` + part + `</a> by <a rel="author" href="/users/writer/pseuds/writer">writer</a></h4></div>
+  <ul class="series"><li>Part <strong>` + part + `</strong> of <a href="/series/777">Baker Street Oddities</a></li></ul>
+</li>`
+	}
+
+	server, client := newPageServer(t, map[string]string{
+		"/series/777": `
+<h2 class="heading">Baker Street Oddities</h2>
+<dl class="series meta group">
+  <dt>Creators:</dt>
+  <dd><a rel="author" href="/users/writer/pseuds/writer">writer</a>, <a rel="author" href="/users/cowriter/pseuds/cowriter">cowriter</a></dd>
+  <dt>Series Begun:</dt><dd>2022-11-05</dd>
+  <dt>Series Updated:</dt><dd>12 Mar 2023</dd>
+  <dt>Description:</dt><dd><blockquote class="userstuff"><p>Small cases.</p></blockquote></dd>
+  <dt>Notes:</dt><dd><blockquote class="userstuff"><p>Read in order.</p></blockquote></dd>
+  <dt>Stats:</dt>
+  <dd><dl class="stats"><dt>Words:</dt><dd>6,000</dd><dt>Works:</dt><dd>2</dd><dt>Complete:</dt><dd>Yes</dd></dl></dd>
+</dl>
+<ul class="series work index group">` + blurb("11111", "1") + `</ul>
+<ol class="pagination actions"><li class="next"><a rel="next" href="/series/777?page=2">Next →</a></li></ol>`,
+		"/series/777?page=2": `<h2 class="heading">Baker Street Oddities</h2>
+<ul class="series work index group">` + blurb("12345", "2") + `</ul>`,
+	})
+
+	series, err := client.GetSeries(context.Background(), 777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if series.ID != 777 || series.URL != server.URL+"/series/777" || series.Title != "Baker Street Oddities" {
+		t.Errorf("unexpected series %+v", series)
+	}
+
+	expectedCreators := []Creator{{Name: "writer", URL: server.URL + "/users/writer/pseuds/writer"}, {Name: "cowriter", URL: server.URL + "/users/cowriter/pseuds/cowriter"}}
+	if !reflect.DeepEqual(series.Creators, expectedCreators) {
+		t.Errorf("expected creators %+v, got %+v", expectedCreators, series.Creators)
+	}
+
+	if series.Begun != "2022-11-05" || series.Updated != "2023-03-12" {
+		t.Errorf("expected the dates to be normalized, got %s and %s", series.Begun, series.Updated)
+	}
+
+	if series.Description != "<p>Small cases.</p>" || series.Notes != "<p>Read in order.</p>" {
+		t.Errorf("unexpected description %q and notes %q", series.Description, series.Notes)
+	}
+
+	if series.Words != 6000 || !series.Complete {
+		t.Errorf("expected 6000 words, complete, got %d words, complete %t", series.Words, series.Complete)
+	}
+
+	if len(series.Works) != 2 || series.Works[0].ID != 11111 || series.Works[1].ID != 12345 || series.Works[1].Series[0].Position != 2 {
+		t.Errorf("expected both pages of works in order, got %+v", series.Works)
+	}
+}
+
+func TestGetUserProfile(t *testing.T) {
+	server, client := newPageServer(t, map[string]string{
+		"/users/writer/profile": `
+<div class="user home profile">
+  <h2 class="heading">writer</h2>
+  <div class="wrapper">
+    <dl class="meta">
+      <dt>My pseuds:</dt>
+      <dd><a href="/users/writer/pseuds/writer">writer</a>, <a href="/users/writer/pseuds/Penname">Penname</a></dd>
+      <dt>I joined on:</dt><dd>2015-06-01</dd>
+      <dt>My user ID is:</dt><dd>123456</dd>
+    </dl>
+  </div>
+  <div class="bio module"><h3 class="heading">Bio</h3><blockquote class="userstuff"><p>Writes <strong>sometimes</strong>.</p></blockquote></div>
+</div>`,
+		"/users/nobody/profile": `<h2 class="heading">Error 500</h2>`,
+	})
+
+	profile, err := client.GetUserProfile(context.Background(), "writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &UserProfile{
+		Name:   "writer",
+		URL:    server.URL + "/users/writer",
+		ID:     123456,
+		Joined: "2015-06-01",
+		Pseuds: []Creator{{Name: "writer", URL: server.URL + "/users/writer/pseuds/writer"}, {Name: "Penname", URL: server.URL + "/users/writer/pseuds/Penname"}},
+		Bio:    "<p>Writes <strong>sometimes</strong>.</p>",
+	}
+
+	if !reflect.DeepEqual(profile, expected) {
+		t.Errorf("expected %+v, got %+v", expected, profile)
+	}
+
+	if _, err := client.GetUserProfile(context.Background(), "nobody"); !errors.Is(err, ErrUnexpectedMarkup) {
+		t.Errorf("expected a page without a profile to be unrecognized, got %v", err)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	_, client := newPageServer(t, map[string]string{
+		"/works/1?view_adult=true":     "redirect:/users/login?restricted=true",
+		"/works/2?view_adult=true":     `<h2 class="heading">Not a work</h2>`,
+		"/users/login?restricted=true": `<form id="loginform"></form>`,
+	})
+
+	for id, expected := range map[int]error{1: ErrRestricted, 2: ErrUnexpectedMarkup, 3: ErrNotFound} {
+		if _, err := client.GetWork(context.Background(), id); !errors.Is(err, expected) {
+			t.Errorf("work %d: expected %v, got %v", id, expected, err)
+		}
+	}
+}
+
+func TestListIterators(t *testing.T) {
+	workBlurb := func(id string) string {
+		return `<li id="work_` + id + `" class="work blurb group" role="article"><div class="header module"><h4 class="heading"><a href="/works/` + id + `">Work ` + id + `</a> by <a rel="author" href="/users/writer/pseuds/writer">writer</a></h4></div></li>`
+	}
+	collectionBlurb := func(name, title string) string {
+		return `<li class="collection picture blurb group" role="article"><div class="header module"><h4 class="heading"><a href="/collections/` + name + `">` + title + `</a></h4></div><blockquote class="userstuff summary"><p>About ` + title + `.</p></blockquote></li>`
+	}
+	next := func(href string) string {
+		return `<ol class="pagination actions"><li class="next"><a rel="next" href="` + href + `">Next →</a></li></ol>`
+	}
+
+	server, client := newPageServer(t, map[string]string{
+		"/users/writer/works":        `<ol class="work index group">` + workBlurb("1") + workBlurb("2") + `</ol>` + next("/users/writer/works?page=2"),
+		"/users/writer/works?page=2": `<ol class="work index group">` + workBlurb("3") + `</ol>` + next("/users/writer/works?page=3"),
+		"/users/reader/bookmarks": `<ol class="bookmark index group">
+<li id="bookmark_1" class="bookmark blurb group" role="article"><div class="header module"><h4 class="heading"><a href="/works/1">Work 1</a></h4></div></li>
+<li id="bookmark_2" class="bookmark blurb group" role="article"><div class="header module"><h4 class="heading"><a href="/series/777">A Series</a></h4></div></li>
+</ol>`,
+		"/users/writer/collections":        `<ul class="collection index group">` + collectionBlurb("fridge_fest_2023", "Fridge Fest 2023") + `</ul>` + next("/users/writer/collections?page=2"),
+		"/users/writer/collections?page=2": `<ul class="collection index group">` + collectionBlurb("small_cases", "Small Cases") + `</ul>`,
+	})
+
+	var works []int
+	var listErr error
+	for work, err := range client.ListUserWorks(context.Background(), "writer") {
+		if err != nil {
+			listErr = err
+			break
+		}
+		works = append(works, work.ID)
+	}
+
+	// the third page is missing, so the listing ends with its error
+	if !slices.Equal(works, []int{1, 2, 3}) || !errors.Is(listErr, ErrNotFound) {
+		t.Errorf("expected works 1 to 3 then a missing page, got %v then %v", works, listErr)
+	}
+
+	var bookmarks []string
+	for bookmark, err := range client.ListUserBookmarks(context.Background(), "reader") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		bookmarks = append(bookmarks, bookmark.ID+" "+bookmark.Item)
+	}
+
+	if expected := []string{"1 " + server.URL + "/works/1", "2 " + server.URL + "/series/777"}; !slices.Equal(bookmarks, expected) {
+		t.Errorf("expected bookmarks %v, got %v", expected, bookmarks)
+	}
+
+	var collections []Collection
+	for collection, err := range client.ListUserCollections(context.Background(), "writer") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		collections = append(collections, collection)
+	}
+
+	expectedCollections := []Collection{
+		{Name: "fridge_fest_2023", URL: server.URL + "/collections/fridge_fest_2023", Title: "Fridge Fest 2023", Summary: "<p>About Fridge Fest 2023.</p>"},
+		{Name: "small_cases", URL: server.URL + "/collections/small_cases", Title: "Small Cases", Summary: "<p>About Small Cases.</p>"},
+	}
+	if !reflect.DeepEqual(collections, expectedCollections) {
+		t.Errorf("expected %+v, got %+v", expectedCollections, collections)
+	}
+
+	// stopping early doesn't fetch the next page
+	before := len(server.Requests())
+	for range client.ListCollections(context.Background(), server.URL+"/users/writer/collections") {
+		break
+	}
+	if requests := server.Requests()[before:]; !slices.Equal(requests, []string{"/users/writer/collections"}) {
+		t.Errorf("expected only the first page to be fetched, got %v", requests)
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	dom, _ := html.Parse(strings.NewReader(`<dl>
+  <dt class="published extra">Published:</dt><dd>2022-12-01</dd>
+  <dt>Series Begun:</dt><dd>2022-11-05</dd>
+  <dt>Tags:</dt><dd>first</dd><dd>second</dd>
+</dl>`))
+
+	var got []string
+	for term, def := range definitions(cascadia.Query(dom, cascadia.MustCompile("dl"))) {
+		got = append(got, term+"="+nodeText(def))
+	}
+
+	// a term's class is used over its text, and a term covers every definition after it
+	expected := []string{"published=2022-12-01", "series begun=2022-11-05", "tags=first", "tags=second"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestParseStats(t *testing.T) {
+	client, err := NewAo3Client("https://archiveofourown.org", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for stats, expected := range map[string]WorkBlurb{
+		`<dt class="language">Language:</dt><dd class="language">English</dd>
+		 <dt class="words">Words:</dt><dd class="words">12,345</dd>
+		 <dt class="chapters">Chapters:</dt><dd class="chapters"><a href="/works/1/chapters/2">3</a>/10</dd>
+		 <dt class="kudos">Kudos:</dt><dd class="kudos">1,000</dd>
+		 <dt class="hits">Hits:</dt><dd class="hits">20,000</dd>`: {Language: "English", Words: 12345, Chapters: 3, TotalChapters: 10, Kudos: 1000, Hits: 20000},
+		`<dt class="chapters">Chapters:</dt><dd class="chapters">4/?</dd>`: {Chapters: 4},
+		`<dt class="words">Words:</dt><dd class="words"></dd>`:             {},
+	} {
+		dom, _ := html.Parse(strings.NewReader(`<dl class="stats">` + stats + `</dl>`))
+
+		var w WorkBlurb
+		client.parseStats(cascadia.Query(dom, client.profile.Page.Stats), &w)
+
+		if !reflect.DeepEqual(w, expected) {
+			t.Errorf("expected %+v from %s, got %+v", expected, stats, w)
+		}
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	for date, expected := range map[string]string{
+		"05 Nov 2022": "2022-11-05",
+		"2022-11-05":  "2022-11-05",
+		"yesterday":   "yesterday",
+		"":            "",
+	} {
+		if got := normalizeDate(date); got != expected {
+			t.Errorf("expected %q to normalize to %q, got %q", date, expected, got)
+		}
+	}
+}
+
+func TestParseBookmarkBlurb(t *testing.T) {
+	client, err := NewAo3Client("https://archiveofourown.org", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dom, _ := html.Parse(strings.NewReader(`<ol class="bookmark index group">
+<li id="bookmark_987" class="bookmark blurb group" role="article">
+  <div class="header module">
+    <h4 class="heading"><a href="/works/12345">A Study in Something</a> by <a rel="author" href="/users/writer/pseuds/writer">writer</a></h4>
+    <h5 class="fandoms heading"><a class="tag" href="/tags/Sherlock%20(TV)/works">Sherlock (TV)</a></h5>
+    <p class="datetime">12 Mar 2023</p>
+  </div>
+  <dl class="stats"><dt class="words">Words:</dt><dd class="words">4,321</dd></dl>
+  <div class="user module group">
+    <h5 class="byline heading">Bookmarked by <a href="/users/reader/pseuds/reader/bookmarks">reader</a></h5>
+    <p class="datetime">01 Apr 2023</p>
+    <p class="status">
+      <span class="rec" title="Rec"><span class="text">Rec</span></span>
+      <span class="private" title="Private Bookmark"><span class="text">Private Bookmark</span></span>
+    </p>
+    <h6 class="landmark heading">Bookmark Tags:</h6>
+    <ul class="meta tags commas"><li><a class="tag" href="/tags/Favorites/bookmarks">Favorites</a></li><li><a class="tag" href="/tags/Reread/bookmarks">Reread</a></li></ul>
+    <h6 class="landmark heading">Bookmark Collections:</h6>
+    <ul class="meta commas"><li><a href="/collections/best_of_2023">Best of 2023</a></li></ul>
+    <h6 class="landmark heading">Bookmarker's Notes</h6>
+    <blockquote class="userstuff notes"><p>So <em>good</em>.</p><p>Read it &amp; reread it.</p></blockquote>
+  </div>
+</li>
+<li id="bookmark_988" class="bookmark blurb group" role="article">
+  <div class="header module">
+    <h4 class="heading"><a href="/series/777">Baker Street Oddities</a> by <a rel="author" href="/users/writer/pseuds/writer">writer</a></h4>
+  </div>
+  <div class="user module group">
+    <h5 class="byline heading">Bookmarked by <a href="/users/reader/pseuds/reader/bookmarks">reader</a></h5>
+    <p class="datetime">2023-04-02</p>
+  </div>
+</li>
+</ol>`))
+
+	blurbs := cascadia.QueryAll(dom, client.profile.Listing.BookmarkBlurb)
+	if len(blurbs) != 2 {
+		t.Fatalf("expected 2 bookmark blurbs, found %d", len(blurbs))
+	}
+
+	bookmark, ok := client.ParseBookmarkBlurb(blurbs[0])
+	if !ok {
+		t.Fatal("expected the work bookmark to be read")
+	}
+
+	if bookmark.ID != "987" || bookmark.Item != "https://archiveofourown.org/works/12345" || bookmark.Bookmarker != "reader" || bookmark.Date != "2023-04-01" {
+		t.Errorf("unexpected bookmark %+v", bookmark)
+	}
+
+	if !slices.Equal(bookmark.Tags, []string{"Favorites", "Reread"}) || !slices.Equal(bookmark.Collections, []string{"https://archiveofourown.org/collections/best_of_2023"}) {
+		t.Errorf("unexpected tags %v and collections %v", bookmark.Tags, bookmark.Collections)
+	}
+
+	// notes keep their markup, so they can be imported again as written
+	if expected := "<p>So <em>good</em>.</p><p>Read it &amp; reread it.</p>"; bookmark.Notes != expected {
+		t.Errorf("expected notes %q, got %q", expected, bookmark.Notes)
+	}
+
+	if !bookmark.Rec || !bookmark.Private {
+		t.Errorf("expected a private rec, got rec %t, private %t", bookmark.Rec, bookmark.Private)
+	}
+
+	// the work's own blurb is read from the same blurb, with its own date
+	if bookmark.Work == nil || bookmark.Work.ID != 12345 || bookmark.Work.Words != 4321 || bookmark.Work.Updated != "2023-03-12" {
+		t.Errorf("unexpected bookmarked work %+v", bookmark.Work)
+	}
+
+	series, ok := client.ParseBookmarkBlurb(blurbs[1])
+	if !ok {
+		t.Fatal("expected the series bookmark to be read")
+	}
+
+	expected := Bookmark{ID: "988", Item: "https://archiveofourown.org/series/777", Bookmarker: "reader", Date: "2023-04-02"}
+	if !reflect.DeepEqual(series, expected) {
+		t.Errorf("expected %+v, got %+v", expected, series)
+	}
+}
@@ -0,0 +1,277 @@
+package ao3client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andybalholm/cascadia"
+)
+
+// region selectors
+
+// Selector is a CSS selector, compiled when it's loaded so that a bad one is
+// caught before any page is read.
+type Selector struct {
+	cascadia.Matcher
+	source string
+}
+
+// ParseSelector compiles a selector, which may be a comma-separated group.
+func ParseSelector(source string) (Selector, error) {
+	if source == "" {
+		return Selector{}, errors.New("Empty selector")
+	}
+
+	group, err := cascadia.ParseGroup(source)
+	if err != nil {
+		return Selector{}, fmt.Errorf("Invalid selector %q: %w", source, err)
+	}
+
+	return Selector{Matcher: group, source: source}, nil
+}
+
+func mustSelector(source string) Selector {
+	s, err := ParseSelector(source)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func (s Selector) String() string {
+	return s.source
+}
+
+func (s Selector) MarshalText() ([]byte, error) {
+	return []byte(s.source), nil
+}
+
+func (s *Selector) UnmarshalText(text []byte) (err error) {
+	*s, err = ParseSelector(string(text))
+	return
+}
+
+// region profile
+
+// Profile is everything that ties AO3Fetch to the site's markup: the selectors
+// used to read its pages, and the names of its login form's fields. When the
+// site changes, a profile file can fix things without a new release.
+type Profile struct {
+	Listing  ListingSelectors  `json:"listing"`
+	Blurb    BlurbSelectors    `json:"blurb"`
+	Bookmark BookmarkSelectors `json:"bookmark"`
+	Work     WorkSelectors     `json:"work"`
+	Page     PageSelectors     `json:"page"`
+	Login    LoginForm         `json:"login"`
+}
+
+// ListingSelectors find the items and pages of a listing.
+type ListingSelectors struct {
+	WorkBlurb       Selector `json:"workBlurb"`
+	BookmarkBlurb   Selector `json:"bookmarkBlurb"`
+	CollectionBlurb Selector `json:"collectionBlurb"`
+	WorkLink        Selector `json:"workLink"`
+	SeriesLink      Selector `json:"seriesLink"`
+	AuthorLink      Selector `json:"authorLink"`
+	NextPage        Selector `json:"nextPage"`
+	LastPage        Selector `json:"lastPage"`
+	ResultCount     Selector `json:"resultCount"`
+}
+
+// BlurbSelectors find a work's fields within its blurb on a listing.
+type BlurbSelectors struct {
+	Title           Selector `json:"title"`
+	Author          Selector `json:"author"`
+	Recipient       Selector `json:"recipient"`
+	Fandom          Selector `json:"fandom"`
+	Rating          Selector `json:"rating"`
+	Category        Selector `json:"category"`
+	Complete        Selector `json:"complete"`
+	Restricted      Selector `json:"restricted"`
+	Date            Selector `json:"date"`
+	Warning         Selector `json:"warning"`
+	Relationship    Selector `json:"relationship"`
+	Character       Selector `json:"character"`
+	Freeform        Selector `json:"freeform"`
+	Summary         Selector `json:"summary"`
+	Series          Selector `json:"series"`
+	CollectionTitle Selector `json:"collectionTitle"`
+}
+
+// BookmarkSelectors find a bookmark's fields within its blurb. Everything but
+// the item is looked for within the user module.
+type BookmarkSelectors struct {
+	Item       Selector `json:"item"`
+	UserModule Selector `json:"userModule"`
+	Bookmarker Selector `json:"bookmarker"`
+	Date       Selector `json:"date"`
+	Tag        Selector `json:"tag"`
+	Notes      Selector `json:"notes"`
+	Rec        Selector `json:"rec"`
+	Private    Selector `json:"private"`
+}
+
+// WorkSelectors find a work's fields on its own page.
+type WorkSelectors struct {
+	Title        Selector `json:"title"`
+	Author       Selector `json:"author"`
+	Summary      Selector `json:"summary"`
+	Association  Selector `json:"association"`
+	Recipient    Selector `json:"recipient"`
+	Restricted   Selector `json:"restricted"`
+	Children     Selector `json:"children"`
+	Rating       Selector `json:"rating"`
+	Warning      Selector `json:"warning"`
+	Category     Selector `json:"category"`
+	Fandom       Selector `json:"fandom"`
+	Relationship Selector `json:"relationship"`
+	Character    Selector `json:"character"`
+	Freeform     Selector `json:"freeform"`
+	Language     Selector `json:"language"`
+	Series       Selector `json:"series"`
+	Collection   Selector `json:"collection"`
+}
+
+// PageSelectors are shared by several kinds of page.
+type PageSelectors struct {
+	Main           Selector `json:"main"` // on every real page, but not on error pages
+	Stats          Selector `json:"stats"`
+	MetaList       Selector `json:"metaList"`
+	Heading        Selector `json:"heading"`
+	Userstuff      Selector `json:"userstuff"`
+	Bio            Selector `json:"bio"`
+	AuthorLink     Selector `json:"authorLink"`
+	WorkLink       Selector `json:"workLink"`
+	SeriesLink     Selector `json:"seriesLink"`
+	CollectionLink Selector `json:"collectionLink"`
+	Link           Selector `json:"link"`
+}
+
+// LoginForm is how the login form is read and filled in.
+type LoginForm struct {
+	Inputs        Selector `json:"inputs"`
+	UsernameField string   `json:"usernameField"`
+	PasswordField string   `json:"passwordField"`
+}
+
+// DefaultProfile returns the built-in profile, which matches AO3 as of this
+// release.
+func DefaultProfile() *Profile {
+	return &Profile{
+		Listing: ListingSelectors{
+			WorkBlurb:       mustSelector(`.index .work.blurb`),
+			BookmarkBlurb:   mustSelector(`.index .bookmark.blurb`),
+			CollectionBlurb: mustSelector(`.index .collection.blurb`),
+			WorkLink:        mustSelector(`.index .blurb .header .heading a[href^="/works/"]`),
+			SeriesLink:      mustSelector(`.index .blurb .header .heading a[href^="/series/"], .index .blurb .series a[href^="/series/"]`),
+			AuthorLink:      mustSelector(`.index .blurb .header .heading a[rel="author"]`),
+			NextPage:        mustSelector(`.pagination a[rel="next"]`),
+			LastPage:        mustSelector(`.pagination li:nth-last-child(2) a`),
+			ResultCount:     mustSelector(`h2.heading`),
+		},
+		Blurb: BlurbSelectors{
+			Title:           mustSelector(`.header .heading a[href^="/works/"]`),
+			Author:          mustSelector(`.header .heading a[rel="author"]`),
+			Recipient:       mustSelector(`.header .heading a[href$="/gifts"]`),
+			Fandom:          mustSelector(`.header .fandoms a.tag`),
+			Rating:          mustSelector(`.required-tags .rating`),
+			Category:        mustSelector(`.required-tags .category`),
+			Complete:        mustSelector(`.required-tags .complete-yes`),
+			Restricted:      mustSelector(`.header .heading img[title="Restricted"]`),
+			Date:            mustSelector(`.header .datetime`),
+			Warning:         mustSelector(`ul.tags .warnings a.tag`),
+			Relationship:    mustSelector(`ul.tags .relationships a.tag`),
+			Character:       mustSelector(`ul.tags .characters a.tag`),
+			Freeform:        mustSelector(`ul.tags .freeforms a.tag`),
+			Summary:         mustSelector(`blockquote.summary`),
+			Series:          mustSelector(`ul.series li`),
+			CollectionTitle: mustSelector(`.header .heading a[href^="/collections/"]`),
+		},
+		Bookmark: BookmarkSelectors{
+			Item:       mustSelector(`.header .heading a[href^="/works/"], .header .heading a[href^="/series/"], .header .heading a[href^="/external_works/"]`),
+			UserModule: mustSelector(`.user.module`),
+			Bookmarker: mustSelector(`.byline a`),
+			Date:       mustSelector(`.datetime`),
+			Tag:        mustSelector(`.meta.tags a.tag`),
+			Notes:      mustSelector(`blockquote.notes`),
+			Rec:        mustSelector(`.status .rec`),
+			Private:    mustSelector(`.status .private`),
+		},
+		Work: WorkSelectors{
+			Title:        mustSelector(`.preface h2.title`),
+			Author:       mustSelector(`.preface .byline a[rel="author"]`),
+			Summary:      mustSelector(`.preface .summary blockquote.userstuff`),
+			Association:  mustSelector(`.preface .associations li`),
+			Recipient:    mustSelector(`.preface .associations a[href$="/gifts"]`),
+			Restricted:   mustSelector(`.preface .title img[title="Restricted"]`),
+			Children:     mustSelector(`#children a[href^="/works/"]`),
+			Rating:       mustSelector(`dl.work.meta dd.rating a.tag`),
+			Warning:      mustSelector(`dl.work.meta dd.warning a.tag`),
+			Category:     mustSelector(`dl.work.meta dd.category a.tag`),
+			Fandom:       mustSelector(`dl.work.meta dd.fandom a.tag`),
+			Relationship: mustSelector(`dl.work.meta dd.relationship a.tag`),
+			Character:    mustSelector(`dl.work.meta dd.character a.tag`),
+			Freeform:     mustSelector(`dl.work.meta dd.freeform a.tag`),
+			Language:     mustSelector(`dl.work.meta dd.language`),
+			Series:       mustSelector(`dl.work.meta dd.series .position`),
+			Collection:   mustSelector(`dl.work.meta dd.collections a`),
+		},
+		Page: PageSelectors{
+			Main:           mustSelector(`#main`),
+			Stats:          mustSelector(`dl.stats`),
+			MetaList:       mustSelector(`dl.meta`),
+			Heading:        mustSelector(`h2.heading`),
+			Userstuff:      mustSelector(`blockquote.userstuff`),
+			Bio:            mustSelector(`.bio blockquote.userstuff`),
+			AuthorLink:     mustSelector(`a[rel="author"]`),
+			WorkLink:       mustSelector(`a[href^="/works/"], a[href^="/external_works/"]`),
+			SeriesLink:     mustSelector(`a[href^="/series/"]`),
+			CollectionLink: mustSelector(`a[href^="/collections/"]`),
+			Link:           mustSelector(`a`),
+		},
+		Login: LoginForm{
+			Inputs:        mustSelector(`#loginform input`),
+			UsernameField: "user[login]",
+			PasswordField: "user[password]",
+		},
+	}
+}
+
+// ReadProfile reads a JSON profile over the built-in one. See Profile.Read.
+func ReadProfile(r io.Reader) (*Profile, error) {
+	return DefaultProfile().Read(r)
+}
+
+// Read returns a copy of the profile with a JSON profile read over it, so the
+// JSON only needs to give what it changes. Unknown names and bad selectors are
+// errors.
+func (p *Profile) Read(r io.Reader) (*Profile, error) {
+	profile := *p
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		return nil, fmt.Errorf("Profile parse failed: %w", err)
+	}
+
+	if profile.Login.UsernameField == "" || profile.Login.PasswordField == "" {
+		return nil, errors.New("Profile parse failed: login fields can't be empty")
+	}
+
+	return &profile, nil
+}
+
+// Load reads a JSON profile file over the profile. See Profile.Read.
+func (p *Profile) Load(name string) (*Profile, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return p.Read(file)
+}
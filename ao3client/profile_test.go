@@ -0,0 +1,46 @@
+package ao3client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+func TestReadProfile(t *testing.T) {
+	profile, err := ReadProfile(strings.NewReader(`{"blurb": {"title": ".header h3 a.title"}, "login": {"usernameField": "user[name]"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := DefaultProfile()
+	if profile.Blurb.Title.String() != ".header h3 a.title" || profile.Login.UsernameField != "user[name]" {
+		t.Errorf("expected the overrides to be used, got %s and %s", profile.Blurb.Title, profile.Login.UsernameField)
+	}
+	if profile.Blurb.Author.String() != defaults.Blurb.Author.String() || profile.Login.PasswordField != defaults.Login.PasswordField {
+		t.Error("expected everything not overridden to keep its built-in value")
+	}
+
+	dom, _ := html.Parse(strings.NewReader(`<ol class="index"><li class="work blurb"><div class="header"><h3><a class="title" href="/works/5">Moved</a></h3></div></li></ol>`))
+	client, err := NewAo3Client("https://archiveofourown.org", Options{Profile: profile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if work, ok := client.ParseWorkBlurb(cascadia.Query(dom, profile.Listing.WorkBlurb)); !ok || work.ID != 5 || work.Title != "Moved" {
+		t.Errorf("expected the blurb to be read with the new selector, got %+v", work)
+	}
+
+	for name, bad := range map[string]string{
+		"bad selector":  `{"work": {"title": "h2[title"}}`,
+		"empty":         `{"work": {"title": ""}}`,
+		"unknown name":  `{"work": {"heading": "h2"}}`,
+		"empty field":   `{"login": {"passwordField": ""}}`,
+		"not an object": `[]`,
+	} {
+		if _, err := ReadProfile(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s: expected the profile to be rejected", name)
+		}
+	}
+}
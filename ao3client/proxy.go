@@ -0,0 +1,147 @@
+package ao3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// region consts
+
+// ProxyDirect is given in place of a proxy URL to connect without a proxy.
+const ProxyDirect = "direct"
+
+var ErrProxyAuth = errors.New("Proxy refused the credentials given, or needs some")
+
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// region config
+
+// ProxyConfig chooses the proxy each request is sent through, by the host
+// it's sent to. Proxy URLs may carry a username and password, which are used
+// to authenticate with the proxy.
+type ProxyConfig struct {
+	// Default is the proxy for hosts not in Hosts. If empty, the HTTP_PROXY,
+	// HTTPS_PROXY, and NO_PROXY environment variables are used.
+	Default string
+	// Hosts maps a host, and its subdomains, to a proxy.
+	Hosts map[string]string
+}
+
+// ParseProxy checks a proxy URL, returning nil for ProxyDirect.
+func ParseProxy(s string) (*url.URL, error) {
+	if s == ProxyDirect {
+		return nil, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		// the parse error would repeat the URL, and its password
+		return nil, errors.New("Proxy isn't a valid URL")
+	}
+
+	if !slices.Contains(proxySchemes, u.Scheme) || u.Host == "" {
+		return nil, fmt.Errorf("Proxy %q must be a URL like socks5://host:port, with a scheme of %s", u.Redacted(), strings.Join(proxySchemes, ", "))
+	}
+
+	return u, nil
+}
+
+// proxyFunc returns a function for http.Transport.Proxy that follows the
+// config.
+func (pc ProxyConfig) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	hosts := map[string]*url.URL{}
+	for host, proxy := range pc.Hosts {
+		u, err := ParseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts[strings.ToLower(host)] = u
+	}
+
+	fallback := http.ProxyFromEnvironment
+	if pc.Default != "" {
+		u, err := ParseProxy(pc.Default)
+		if err != nil {
+			return nil, err
+		}
+
+		fallback = func(*http.Request) (*url.URL, error) { return u, nil }
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+
+		for host != "" {
+			if u, ok := hosts[host]; ok {
+				return u, nil
+			}
+
+			_, host, _ = strings.Cut(host, ".")
+		}
+
+		return fallback(req)
+	}, nil
+}
+
+// region errors
+
+// ProxyError is a request that failed at the proxy, before reaching the site.
+type ProxyError struct {
+	Proxy string // without its password
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("Proxy %s failed: %v", e.Proxy, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// proxyConnectError fails a tunnel for an HTTPS request that the proxy
+// refused. Without it, the refusal is a plain error that looks like the site's.
+func proxyConnectError(_ context.Context, proxy *url.URL, _ *http.Request, resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusProxyAuthRequired:
+		return &ProxyError{Proxy: proxy.Redacted(), Err: ErrProxyAuth}
+	default:
+		return &ProxyError{Proxy: proxy.Redacted(), Err: fmt.Errorf("CONNECT refused: %s", resp.Status)}
+	}
+}
+
+// proxyError returns a ProxyError if the request failed at its proxy.
+func (c *Ao3Client) proxyError(req *http.Request, resp *http.Response, err error) error {
+	proxy, _ := c.proxy(req)
+	if proxy == nil {
+		return nil
+	}
+
+	// HTTPS requests are tunnelled, and a refused tunnel is already one
+	var proxyErr *ProxyError
+	if errors.As(err, &proxyErr) {
+		return proxyErr
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return &ProxyError{Proxy: proxy.Redacted(), Err: opErr.Err}
+	}
+
+	// plain HTTP requests go to the proxy as is, so it answers them itself
+	if err == nil && resp.StatusCode == http.StatusProxyAuthRequired {
+		resp.Body.Close()
+		return &ProxyError{Proxy: proxy.Redacted(), Err: ErrProxyAuth}
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package ao3client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/scheduler"
+)
+
+// region consts
+
+var ErrNotRecorded = errors.New("Request not in recording")
+
+// unrecordedHeaders are left out of recordings, so they can be shared without
+// giving away a login.
+var unrecordedHeaders = []string{"Set-Cookie"}
+
+// region recording
+
+// exchange is one request made through a client, and what came back. Only the
+// method and URL of the request are kept; its headers and body would include
+// cookies and passwords.
+type exchange struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	FinalURL string      `json:"finalUrl,omitempty"` // after redirects
+	Status   int         `json:"status,omitempty"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body,omitempty"`
+
+	// errors are kept by kind as well as message, so that they replay as the
+	// same kind: a timeout is retried, where an unknown error isn't
+	Error       string `json:"error,omitempty"`
+	ErrorKind   string `json:"errorKind,omitempty"`
+	ErrorDetail string `json:"errorDetail,omitempty"` // what the kind of error needs to be rebuilt
+	Proxy       string `json:"proxy,omitempty"`       // without its password
+	Wait        int    `json:"wait,omitempty"`        // seconds
+}
+
+func (e exchange) key() string {
+	return e.Method + " " + e.URL
+}
+
+// Recorder writes every exchange made through a client, one JSON object per
+// line, for replaying later.
+type Recorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// Err returns the first error met writing the recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// record writes an exchange, returning the response with its body still
+// readable.
+func (r *Recorder) record(req *http.Request, resp *http.Response, err error) *http.Response {
+	ex := exchange{Method: req.Method, URL: req.URL.String()}
+
+	if err != nil {
+		ex.recordError(err)
+	} else {
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		if readErr != nil {
+			r.fail(readErr)
+			return resp
+		}
+
+		ex.FinalURL = resp.Request.URL.String()
+		ex.Status = resp.StatusCode
+		ex.Header = resp.Header.Clone()
+		ex.Body = string(body)
+
+		for _, header := range unrecordedHeaders {
+			ex.Header.Del(header)
+		}
+	}
+
+	line, marshalErr := json.Marshal(ex)
+	if marshalErr != nil {
+		r.fail(marshalErr)
+		return resp
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, writeErr := r.w.Write(append(line, '\n')); writeErr != nil && r.err == nil {
+		r.err = writeErr
+	}
+
+	return resp
+}
+
+func (r *Recorder) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// recordError keeps an error's message, and what's needed to give back the
+// same kind of error on replay.
+func (e *exchange) recordError(err error) {
+	e.Error = err.Error()
+
+	var unavailable *UnavailableError
+	var proxyErr *ProxyError
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+
+	switch {
+	case errors.Is(err, scheduler.ErrBudgetSpent):
+		e.ErrorKind = "budget"
+	case errors.Is(err, ErrNotCached):
+		e.ErrorKind = "notCached"
+	case errors.As(err, &unavailable):
+		e.ErrorKind = "unavailable"
+		e.ErrorDetail = unavailable.Reason
+		e.Wait = int(unavailable.Wait.Seconds())
+	case errors.As(err, &proxyErr):
+		e.ErrorKind = "proxy"
+		e.Proxy = proxyErr.Proxy
+		if errors.Is(err, ErrProxyAuth) {
+			e.ErrorKind = "proxyAuth"
+		} else if proxyErr.Err != nil {
+			e.ErrorDetail = proxyErr.Err.Error()
+		}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		e.ErrorKind = "timeout"
+	case errors.As(err, &dnsErr):
+		e.ErrorKind = "dns"
+		e.ErrorDetail = dnsErr.Name
+		if dnsErr.IsTemporary {
+			e.ErrorKind = "dnsTemporary"
+		}
+	case errors.As(err, &opErr):
+		e.ErrorKind = "network"
+		e.ErrorDetail = opErr.Err.Error()
+	}
+}
+
+// replayError rebuilds a recorded error, with its recorded message.
+func (e exchange) replayError() error {
+	var err error
+
+	switch e.ErrorKind {
+	case "budget":
+		err = scheduler.ErrBudgetSpent
+	case "notCached":
+		err = ErrNotCached
+	case "unavailable":
+		err = &UnavailableError{Reason: e.ErrorDetail, Wait: time.Duration(e.Wait) * time.Second}
+	case "proxyAuth":
+		err = &ProxyError{Proxy: e.Proxy, Err: ErrProxyAuth}
+	case "proxy":
+		err = &ProxyError{Proxy: e.Proxy, Err: errors.New(e.ErrorDetail)}
+	case "timeout":
+		err = context.DeadlineExceeded
+	case "dns", "dnsTemporary":
+		err = &net.DNSError{Err: e.Error, Name: e.ErrorDetail, IsTemporary: e.ErrorKind == "dnsTemporary"}
+	case "network":
+		err = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New(e.ErrorDetail)}
+	default:
+		return errors.New(e.Error)
+	}
+
+	return &replayedError{message: e.Error, err: err}
+}
+
+// replayedError is a recorded error, of the kind it was recorded as.
+type replayedError struct {
+	message string
+	err     error
+}
+
+func (e *replayedError) Error() string {
+	return e.message
+}
+
+func (e *replayedError) Unwrap() error {
+	return e.err
+}
+
+// region replay
+
+// Replayer serves the exchanges in a recording back, without the network.
+// Requests repeated in the recording get their answers in the order recorded;
+// past the last, the last answer is given again.
+type Replayer struct {
+	mu        sync.Mutex
+	exchanges map[string][]exchange
+}
+
+// NewReplayer reads a recording made by a Recorder.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	p := &Replayer{exchanges: map[string][]exchange{}}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var ex exchange
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("Recording line %d unreadable: %w", line, err)
+		}
+
+		p.exchanges[ex.key()] = append(p.exchanges[ex.key()], ex)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *Replayer) replay(req *http.Request) (*http.Response, error) {
+	p.mu.Lock()
+	key := req.Method + " " + req.URL.String()
+	recorded := p.exchanges[key]
+
+	if len(recorded) == 0 {
+		p.mu.Unlock()
+		return nil, ErrNotRecorded
+	}
+
+	ex := recorded[0]
+	if len(recorded) > 1 {
+		p.exchanges[key] = recorded[1:]
+	}
+	p.mu.Unlock()
+
+	if ex.Error != "" {
+		return nil, ex.replayError()
+	}
+
+	finalReq := req.Clone(req.Context())
+	if finalURL, err := url.Parse(ex.FinalURL); err == nil {
+		finalReq.URL = finalURL
+	}
+
+	header := ex.Header
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(ex.Body))),
+		ContentLength: int64(len(ex.Body)),
+		Request:       finalReq,
+	}, nil
+}
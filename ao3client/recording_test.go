@@ -0,0 +1,205 @@
+package ao3client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/scheduler"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	visits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visits++
+		http.SetCookie(w, &http.Cookie{Name: "_otwarchive_session", Value: "secret"})
+		io.WriteString(w, "visit "+strconv.Itoa(visits))
+	}))
+
+	recording := &bytes.Buffer{}
+	recorder := NewRecorder(recording)
+
+	client, err := NewAo3Client(server.URL, Options{Scheduler: scheduler.New(scheduler.Config{Burst: 10}), Record: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		resp, err := client.Get(context.Background(), server.URL+"/works/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	server.Close()
+
+	if recorder.Err() != nil {
+		t.Fatal(recorder.Err())
+	}
+
+	if strings.Contains(recording.String(), "secret") {
+		t.Error("expected cookies to be left out of the recording")
+	}
+
+	replayer, err := NewReplayer(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replaying, err := NewAo3Client(server.URL, Options{Replay: replayer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// answers come back in the order recorded, then the last one repeats
+	for _, expected := range []string{"visit 1", "visit 2", "visit 2"} {
+		resp, err := replaying.Get(context.Background(), server.URL+"/works/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != expected || resp.Request.URL.Path != "/works/1" {
+			t.Errorf("expected %q, replayed %q from %s", expected, body, resp.Request.URL)
+		}
+	}
+
+	if _, err := replaying.Get(context.Background(), server.URL+"/works/2"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected an unrecorded request to fail, got %v", err)
+	}
+}
+
+func TestReplayTimeoutThenSuccess(t *testing.T) {
+	// the timed out request is still being answered when it's retried
+	var visits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visit := visits.Add(1)
+		if visit == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		io.WriteString(w, "visit "+strconv.Itoa(int(visit)))
+	}))
+	defer server.Close()
+
+	recording := &bytes.Buffer{}
+	recorder := NewRecorder(recording)
+
+	client, err := NewAo3Client(server.URL, Options{Scheduler: scheduler.New(scheduler.Config{Burst: 10}), ResponseHeaderTimeout: 50 * time.Millisecond, Record: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var timeout error
+	for range 2 {
+		resp, err := client.Get(context.Background(), server.URL+"/works/1")
+		if err != nil {
+			timeout = err
+			continue
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	var netErr net.Error
+	if !errors.As(timeout, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected the first request to time out, got %v", timeout)
+	}
+
+	replayer, err := NewReplayer(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replaying, err := NewAo3Client(server.URL, Options{Replay: replayer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the timeout comes back as a timeout, so a replayed crawl retries it as the
+	// recorded one did
+	_, err = replaying.Get(context.Background(), server.URL+"/works/1")
+	if !errors.Is(err, context.DeadlineExceeded) || err.Error() != timeout.Error() {
+		t.Fatalf("expected the recorded timeout, replayed %v", err)
+	}
+
+	resp, err := replaying.Get(context.Background(), server.URL+"/works/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body, _ := io.ReadAll(resp.Body); string(body) != "visit 2" {
+		t.Errorf("expected the retry to succeed, replayed %q", body)
+	}
+}
+
+func TestReplayErrorKinds(t *testing.T) {
+	errs := []error{
+		&UnavailableError{Reason: reasonRetryLater, Wait: retryLaterWait},
+		&ProxyError{Proxy: "http://proxy.example", Err: ErrProxyAuth},
+		&ProxyError{Proxy: "http://proxy.example", Err: errors.New("CONNECT refused: 502 Bad Gateway")},
+		&net.DNSError{Err: "no such host", Name: "archiveofourown.org", IsTemporary: true},
+		&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")},
+		scheduler.ErrBudgetSpent,
+		ErrNotCached,
+		errors.New("something else"),
+	}
+
+	recording := &bytes.Buffer{}
+	recorder := NewRecorder(recording)
+	req := httptest.NewRequest(http.MethodGet, "https://archiveofourown.org/works/1", nil)
+	for _, err := range errs {
+		recorder.record(req, nil, err)
+	}
+
+	replayer, err := NewReplayer(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, recorded := range errs {
+		_, replayed := replayer.replay(req)
+
+		if replayed == nil || replayed.Error() != recorded.Error() {
+			t.Errorf("expected %q, replayed %v", recorded, replayed)
+			continue
+		}
+
+		var unavailable *UnavailableError
+		var proxyErr *ProxyError
+		var dnsErr *net.DNSError
+		var opErr *net.OpError
+
+		switch recorded := recorded.(type) {
+		case *UnavailableError:
+			if !errors.As(replayed, &unavailable) || *unavailable != *recorded {
+				t.Errorf("expected %#v, replayed %#v", recorded, unavailable)
+			}
+		case *ProxyError:
+			if !errors.As(replayed, &proxyErr) || proxyErr.Proxy != recorded.Proxy || errors.Is(replayed, ErrProxyAuth) != errors.Is(recorded, ErrProxyAuth) {
+				t.Errorf("expected %#v, replayed %#v", recorded, proxyErr)
+			}
+		case *net.DNSError:
+			if !errors.As(replayed, &dnsErr) || dnsErr.Name != recorded.Name || dnsErr.IsTemporary != recorded.IsTemporary {
+				t.Errorf("expected %#v, replayed %#v", recorded, dnsErr)
+			}
+		case *net.OpError:
+			if !errors.As(replayed, &opErr) || opErr.Err.Error() != recorded.Err.Error() {
+				t.Errorf("expected %#v, replayed %#v", recorded, opErr)
+			}
+		default:
+			if recorded.Error() != "something else" && !errors.Is(replayed, recorded) {
+				t.Errorf("expected %v, replayed %v", recorded, replayed)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package ao3client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// region consts
+
+const reasonRetryLater = "asked to retry later"
+const retryLaterWait = 5 * time.Minute
+const maintenanceWait = 15 * time.Minute
+
+// AO3's rate limit page is just these words, even when it's sent as a success.
+var retryLaterMatcher = regexp.MustCompile(`(?i)^\s*retry later\s*$`)
+var maintenanceMatcher = regexp.MustCompile(`(?i)down for maintenance|under maintenance|maintenance mode|scheduled maintenance`)
+var errorPageMatcher = regexp.MustCompile(`(?i)502 bad gateway|503 service (temporarily )?unavailable|504 gateway time-?out|responding too slowly|web server is returning an unknown error`)
+
+// region error
+
+// UnavailableError is a page that came back as a success, but whose body says
+// the site couldn't serve it: a "Retry later" rate limit, a maintenance page,
+// or an error page from something in front of the site.
+type UnavailableError struct {
+	Reason string
+	Wait   time.Duration // how long requests are paused for; 0 if they only back off
+}
+
+func (e *UnavailableError) Error() string {
+	return "Site unavailable: " + e.Reason
+}
+
+// checkBody reads a successful response's body, to turn one that only
+// pretends to be a page into an *UnavailableError. The body is left readable.
+func (c *Ao3Client) checkBody(resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode/100 != 2 {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := c.unavailable(body); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Ao3Client) unavailable(body []byte) *UnavailableError {
+	if retryLaterMatcher.Match(body) {
+		return &UnavailableError{Reason: reasonRetryLater, Wait: retryLaterWait}
+	}
+
+	maintenance := maintenanceMatcher.Match(body)
+	if !maintenance && !errorPageMatcher.Match(body) {
+		return nil
+	}
+
+	// the words could be in a work's summary; a real page has the archive's layout
+	dom, err := html.Parse(bytes.NewReader(body))
+	if err == nil && cascadia.Query(dom, c.profile.Page.Main) != nil {
+		return nil
+	}
+
+	if maintenance {
+		return &UnavailableError{Reason: "down for maintenance", Wait: maintenanceWait}
+	}
+
+	return &UnavailableError{Reason: "served an error page"}
+}
@@ -0,0 +1,167 @@
+// Package archive describes the sites AO3Fetch can crawl. They all run the
+// otwarchive software, but differ in where they live, how hard they can be
+// crawled, and sometimes in their markup.
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/ao3client"
+	"github.com/legowerewolf/AO3fetch/scheduler"
+)
+
+// region archive
+
+// Archive is an otwarchive site, as described in an archives file.
+type Archive struct {
+	Name      string          `json:"-"`
+	BaseURL   string          `json:"baseUrl"`
+	Mirrors   []string        `json:"mirrors,omitempty"`   // other hosts serving the same site
+	MinDelay  int             `json:"minDelay"`            // fewest seconds between requests; no less than 10
+	UserAgent string          `json:"userAgent,omitempty"` // sent instead of AO3Fetch's own, if the site asks for something else
+	Login     bool            `json:"login"`               // whether logging in is supported
+	Selectors json.RawMessage `json:"selectors,omitempty"` // overrides for the built-in parser profile
+
+	profile *ao3client.Profile
+}
+
+// AO3 is the Archive of Our Own, which AO3Fetch was written for.
+var AO3 = &Archive{
+	Name:     "ao3",
+	BaseURL:  "https://archiveofourown.org",
+	Mirrors:  []string{"www.archiveofourown.org", "archiveofourown.gay", "www.archiveofourown.gay", "archive.transformativeworks.org"},
+	MinDelay: 10,
+	Login:    true,
+}
+
+// Hosts returns every host the archive answers on, its own first.
+func (a *Archive) Hosts() []string {
+	base, _ := url.Parse(a.BaseURL)
+
+	return append([]string{base.Hostname()}, a.Mirrors...)
+}
+
+// Serves reports whether host is one of the archive's.
+func (a *Archive) Serves(host string) bool {
+	return slices.ContainsFunc(a.Hosts(), func(h string) bool { return strings.EqualFold(h, host) })
+}
+
+// FailoverHosts returns the hosts to fail over to from the crawled one, in
+// order. Hosts differing only by "www." are the same site, so only the first of
+// them is given, and none for the crawled site.
+func (a *Archive) FailoverHosts(crawled string) []string {
+	seen := map[string]bool{strings.TrimPrefix(strings.ToLower(crawled), "www."): true}
+
+	var hosts []string
+	for _, host := range a.Hosts() {
+		site := strings.TrimPrefix(strings.ToLower(host), "www.")
+		if !seen[site] {
+			seen[site] = true
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+// Profile returns the parser profile for the archive's pages.
+func (a *Archive) Profile() *ao3client.Profile {
+	if a.profile == nil {
+		return ao3client.DefaultProfile()
+	}
+
+	return a.profile
+}
+
+func (a *Archive) validate() error {
+	u, err := url.Parse(a.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("baseUrl %q is not an http(s) URL", a.BaseURL)
+	}
+
+	// the scheduler never goes faster than its own minimum, whatever an archive says
+	if time.Duration(a.MinDelay)*time.Second < scheduler.MinimumDelay {
+		return fmt.Errorf("minDelay must be at least %d seconds", int(scheduler.MinimumDelay.Seconds()))
+	}
+
+	if len(a.Selectors) > 0 {
+		if a.profile, err = ao3client.ReadProfile(bytes.NewReader(a.Selectors)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// region registry
+
+// Registry is a set of archives, by name.
+type Registry map[string]*Archive
+
+// Builtin returns a registry of the archives AO3Fetch knows about itself.
+func Builtin() Registry {
+	return Registry{AO3.Name: AO3}
+}
+
+// Load adds the archives in a JSON file, an object of archives by name. Built-in
+// archives can't be replaced; their markup can be fixed with a profile instead.
+func (r Registry) Load(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var archives map[string]*Archive
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&archives); err != nil {
+		return fmt.Errorf("Archives parse failed: %w", err)
+	}
+
+	var errs []error
+	for _, archiveName := range slices.Sorted(maps.Keys(archives)) {
+		a := archives[archiveName]
+
+		switch {
+		case a == nil:
+			errs = append(errs, fmt.Errorf("%s: no settings given", archiveName))
+		case Builtin()[archiveName] != nil:
+			errs = append(errs, fmt.Errorf("%s: is built in, so can't be redefined", archiveName))
+		default:
+			if err := a.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", archiveName, err))
+				continue
+			}
+
+			a.Name = archiveName
+			r[archiveName] = a
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ForHost returns the archive serving host, or nil if none does.
+func (r Registry) ForHost(host string) *Archive {
+	for _, name := range r.Names() {
+		if r[name].Serves(host) {
+			return r[name]
+		}
+	}
+
+	return nil
+}
+
+// Names returns the registry's archive names in order.
+func (r Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r))
+}
@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeArchives(t *testing.T, content string) string {
+	name := filepath.Join(t.TempDir(), "archives.json")
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return name
+}
+
+func TestLoad(t *testing.T) {
+	registry := Builtin()
+
+	err := registry.Load(writeArchives(t, `{
+		"squidge": {
+			"baseUrl": "https://squidgeworld.org",
+			"mirrors": ["www.squidgeworld.org"],
+			"minDelay": 15,
+			"selectors": {"listing": {"lastPage": ".pagination li.last a"}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	squidge := registry.ForHost("WWW.squidgeworld.org")
+	if squidge == nil || squidge.Name != "squidge" || squidge.Login {
+		t.Fatalf("expected the loaded archive to serve its mirror, got %+v", squidge)
+	}
+
+	if squidge.Profile().Listing.LastPage.String() != ".pagination li.last a" {
+		t.Errorf("expected the archive's selectors to be used, got %s", squidge.Profile().Listing.LastPage)
+	}
+
+	if hosts := AO3.FailoverHosts("www.archiveofourown.org"); !slices.Equal(hosts, []string{"archiveofourown.gay", "archive.transformativeworks.org"}) {
+		t.Errorf("expected to fail over to the other domains, got %v", hosts)
+	}
+
+	if registry.ForHost("archiveofourown.gay") != AO3 || registry.ForHost("example.com") != nil {
+		t.Error("expected hosts to be matched to their archives")
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	for name, content := range map[string]string{
+		"built in":      `{"ao3": {"baseUrl": "https://archiveofourown.org", "minDelay": 1}}`,
+		"no base URL":   `{"other": {"minDelay": 10}}`,
+		"no delay":      `{"other": {"baseUrl": "https://example.com"}}`,
+		"short delay":   `{"other": {"baseUrl": "https://example.com", "minDelay": 5}}`,
+		"bad selectors": `{"other": {"baseUrl": "https://example.com", "minDelay": 10, "selectors": {"work": {"title": "h2["}}}}`,
+		"unknown field": `{"other": {"baseUrl": "https://example.com", "minDelay": 10, "delay": 10}}`,
+	} {
+		if err := Builtin().Load(writeArchives(t, content)); err == nil {
+			t.Errorf("%s: expected the archives to be rejected", name)
+		}
+	}
+}
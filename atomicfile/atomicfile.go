@@ -0,0 +1,42 @@
+// Package atomicfile writes files that other processes may be reading.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces a file in one step, so readers never see it half written,
+// even if the machine crashes. Concurrent writes to the same file each write
+// their own temporary file, so the last to finish wins whole.
+func WriteFile(path string, contents []byte) (err error) {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if err = temp.Chmod(0o644); err != nil {
+		return err
+	}
+
+	if _, err = temp.Write(contents); err != nil {
+		return err
+	}
+
+	if err = temp.Sync(); err != nil {
+		return err
+	}
+
+	if err = temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,56 @@
+package atomicfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state")
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			if err := WriteFile(path, bytes.Repeat([]byte{'a' + byte(i)}, 64*1024)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one write, whole, and nothing else
+	if len(contents) != 64*1024 || len(bytes.Trim(contents, string(contents[:1]))) != 0 {
+		t.Errorf("expected one whole write, got %d bytes mixing writes", len(contents))
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected temporary files to be gone, found %d files", len(entries))
+	}
+}
+
+func TestWriteFileFails(t *testing.T) {
+	dir := t.TempDir()
+
+	// a directory can't be replaced by a file
+	path := filepath.Join(dir, "taken")
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(path, []byte("contents")); err == nil {
+		t.Error("expected replacing a directory to fail")
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected the temporary file to be removed, found %d files", len(entries))
+	}
+}
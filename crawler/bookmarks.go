@@ -0,0 +1,22 @@
+package crawler
+
+import (
+	"github.com/legowerewolf/AO3fetch/ao3client"
+)
+
+// Record is a single entry of crawl output: a work, or any other bookmarked
+// item, along with the bookmarks that were found for it and the path by which
+// it was reached.
+type Record struct {
+	URL       string               `json:"url"`
+	Via       Path                 `json:"via,omitempty"`
+	Bookmarks []ao3client.Bookmark `json:"bookmarks,omitempty"`
+}
+
+func bookmarkKey(b ao3client.Bookmark) string {
+	if b.ID != "" {
+		return b.ID
+	}
+
+	return b.Item + "#" + b.Bookmarker
+}
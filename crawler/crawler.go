@@ -1,12 +1,14 @@
 package crawler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
+	"net"
 	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -19,38 +21,62 @@ import (
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/gammazero/deque"
 	"github.com/legowerewolf/AO3fetch/ao3client"
+	"github.com/legowerewolf/AO3fetch/graph"
 	"github.com/legowerewolf/AO3fetch/logbuffer"
 	"github.com/legowerewolf/AO3fetch/osc"
+	"github.com/legowerewolf/AO3fetch/scheduler"
+	"github.com/legowerewolf/AO3fetch/search"
+	"github.com/legowerewolf/AO3fetch/seeds"
 	"golang.org/x/net/html"
 )
 
 // region consts
 
-const delayBackoffFactor = 1.3
-const delayDecayFactor = 0.9
+const maxDeadLettersShown = 5
 
 var isSeriesMatcher = regexp.MustCompile(`/series/\d+`)
 
-var workSelector = mustParseSelector(`.index .blurb .header .heading a[href^="/works/"]`)
-var seriesSelector = mustParseSelector(`.index .blurb .header .heading a[href^="/series/"], .index .blurb .series a[href^="/series/"]`)
-var paginationSelector = mustParseSelector(`.pagination li:nth-last-child(2) a`)
+const orphanAccount = "orphan_account"
+
+// region config
+
+// Config holds the settings for a crawl.
+type Config struct {
+	Pages             int // -1 to autodetect
+	PartitionMaxPages int // split partitions with more pages than this; 0 disables
+	Discovery         Discovery
+	Retry             RetryPolicy
+}
 
 // region runtime model
 
 type RuntimeModel struct {
 	client *ao3client.Ao3Client
+	ctx    context.Context    // cancelled when the crawl is aborted
+	cancel context.CancelFunc // aborts in-flight requests
 
 	// config properties
-	includeSeries  bool
-	autodetectStop bool
-	delay          time.Duration
+	autodetectStop    bool
+	partitionMaxPages int
+	discovery         Discovery
+	retry             RetryPolicy
 
 	// work and series data
-	queue        deque.Deque[string] // stores URLs to be crawled
-	queueSet     mapset.Set[string]  // stores URLs that have been queued to be crawled
-	workSet      mapset.Set[string]  // stores URLs of works that have been detected
-	seriesSet    mapset.Set[string]  // ditto for series
+	queue        deque.Deque[string]           // stores URLs to be crawled
+	queueSet     mapset.Set[string]            // stores URLs that have been queued to be crawled
+	workSet      mapset.Set[string]            // stores URLs of works that have been detected
+	discovered   map[Edge]mapset.Set[string]   // stores URLs discovered along each kind of edge
+	paths        map[string]Path               // stores how each listing, work, and bookmarked item was reached
+	bookmarks    map[string]ao3client.Bookmark // stores bookmarker data, keyed by bookmark ID
+	graph        *graph.Graph                  // stores relationships between works, series, and creators
+	listings     map[string]*listingState      // stores what's been seen of each listing, for drift detection
+	failures     map[string]*failure           // stores the error history of URLs that are being retried
+	deadLetters  []DeadLetter                  // stores URLs that were given up on
 	pagesCrawled int
+	shiftedWorks int
+
+	unrecognizedPages []string // listing pages that counted results, but yielded none
+	host              string   // host requests were last sent to, to notice failing over
 
 	// logging
 	Logs   logbuffer.LogBuffer
@@ -60,9 +86,10 @@ type RuntimeModel struct {
 	startTime time.Time
 
 	// control
-	nextCrawlTime   time.Time
-	currentDelay    time.Duration
 	crawlInProgress bool
+	inFlight        string // the URL being crawled, while crawlInProgress
+	waitingToRetry  bool
+	limitReached    map[Edge]bool
 
 	// view props
 	width  int
@@ -73,22 +100,44 @@ type RuntimeModel struct {
 	spin spinner.Model
 }
 
-func InitRuntimeModel(includeSeries bool, delay int, seedURL url.URL, pages int, client *ao3client.Ao3Client) (m RuntimeModel) {
+func InitRuntimeModel(config Config, seedList []seeds.Seed, client *ao3client.Ao3Client) (m RuntimeModel) {
 	m.client = client
+	if client != nil {
+		m.host = client.Host()
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
 
-	m.includeSeries = includeSeries
-	m.delay = time.Duration(delay) * time.Second
-	m.currentDelay = m.delay
+	m.partitionMaxPages = config.PartitionMaxPages
+	m.discovery = config.Discovery
+	m.retry = config.Retry
 
 	m.workSet = mapset.NewSet[string]()
-	m.seriesSet = mapset.NewSet[string]()
+	m.discovered = newDiscoveredSets()
+	m.paths = make(map[string]Path)
 	m.queueSet = mapset.NewSet[string]()
-
-	if pages > 0 {
-		m.queueUrlRange(seedURL, pages)
-	} else {
-		m.autodetectStop = true
-		m.queueUrl(seedURL.String())
+	m.bookmarks = make(map[string]ao3client.Bookmark)
+	m.graph = graph.New()
+	m.listings = make(map[string]*listingState)
+	m.limitReached = make(map[Edge]bool)
+	m.failures = make(map[string]*failure)
+
+	for _, seed := range seedList {
+		m.setPath(seed.URL.String(), Path{{Edge: EdgeSeed, URL: seed.URL.String()}})
+
+		switch {
+		case !seed.Kind.IsListing():
+			m.workSet.Add(seed.URL.String())
+			m.visitWork(seed.URL.String())
+		case isSeriesMatcher.MatchString(seed.URL.Path):
+			m.discovered[EdgeSeries].Add(seed.URL.String())
+			m.queueUrl(seed.URL.String())
+		case config.Pages > 0:
+			// a seed past the last page to crawl, like one from a failures file, is still crawled itself
+			m.queueUrlRange(seed.URL, max(config.Pages, getPageNum(seed.URL)))
+		default:
+			m.autodetectStop = true
+			m.queueUrl(seed.URL.String())
+		}
 	}
 
 	m.prog = progress.New()
@@ -114,15 +163,24 @@ type crawlResponseMsg struct {
 	Success  bool
 
 	// fail fields
-	Retryable bool
-	Fatal     bool
-	ErrMsg    string
-	WaitFor   int // seconds
+	Retryable   bool
+	Fatal       bool
+	BudgetSpent bool // no request was made
+	Canceled    bool // the crawl was aborted
+	ErrMsg      string
+	WaitFor     int // seconds
 
 	// success fields
 	AddWorks         []string
 	AddSeries        []string
+	AddAuthors       []string
+	AddRelated       []string
+	AddCollections   []string
+	AddBookmarks     []ao3client.Bookmark
+	AddBlurbs        []ao3client.WorkBlurb
+	Work             *ao3client.Work // set when a work's own page was crawled
 	LastDetectedPage int
+	ResultCount      int
 }
 
 // region program view/init/update
@@ -143,18 +201,30 @@ func (m RuntimeModel) View() string {
 
 	// current stats
 
+	sched := m.client.Scheduler()
+	nextCrawlTime := sched.Next()
+
+	budgetHour, budgetDay, budgetRefresh, hasBudget := sched.Remaining()
+	windowOpen, windowOpens := sched.WindowOpen()
+
 	// current action
 	currentAction := fmt.Sprintf("Requesting%s", m.spin.View())
-	if !m.crawlInProgress {
-		currentAction = fmt.Sprintf("Sleeping %s", time.Until(m.nextCrawlTime).Round(time.Second).String())
+	if !m.crawlInProgress && !windowOpen {
+		currentAction = fmt.Sprintf("Waiting for window at %s", windowOpens.Local().Format("15:04"))
+	} else if !m.crawlInProgress && !budgetRefresh.IsZero() {
+		currentAction = fmt.Sprintf("Waiting for budget until %s", budgetRefresh.Local().Format("15:04"))
+	} else if m.waitingToRetry {
+		currentAction = "Waiting to retry"
+	} else if !m.crawlInProgress {
+		currentAction = fmt.Sprintf("Sleeping %s", time.Until(nextCrawlTime).Round(time.Second).String())
 	}
 
 	// estimated completion time
-	eta := m.nextCrawlTime
+	eta := nextCrawlTime
 	if m.crawlInProgress {
 		eta = time.Now()
 	}
-	eta = eta.Add(m.currentDelay * time.Duration(m.queue.Len()-1))
+	eta = sched.ETA(eta, m.queue.Len()-1)
 
 	// total number of pages
 	totalPages := m.pagesCrawled + m.queue.Len()
@@ -162,10 +232,13 @@ func (m RuntimeModel) View() string {
 		totalPages += 1
 	}
 
-	// what to show for series
-	series := "Ignoring series"
-	if m.includeSeries {
-		series = fmt.Sprintf("Series discovered: %d", m.seriesSet.Cardinality())
+	// what to show for each kind of discovery
+	discovered := func(rule Rule, edge Edge, name string) string {
+		if !rule.Enabled {
+			return "Ignoring " + strings.ToLower(name)
+		}
+
+		return fmt.Sprintf("%s discovered: %d", name, m.discovered[edge].Cardinality())
 	}
 
 	// batch all of the stats above into one list
@@ -175,12 +248,36 @@ func (m RuntimeModel) View() string {
 		fmt.Sprintf("ETA: %s (%s)", eta.Local().Format("15:04:05"), time.Until(eta).Round(time.Second)),
 		fmt.Sprintf("Elapsed: %s", time.Since(m.startTime).Round(time.Second)),
 		fmt.Sprintf("Works discovered: %d", m.workSet.Cardinality()),
-		series,
+		discovered(m.discovery.Series, EdgeSeries, "Series"),
+		discovered(m.discovery.Authors, EdgeAuthor, "Authors"),
+		discovered(m.discovery.Related, EdgeRelated, "Related works"),
+		discovered(m.discovery.Collections, EdgeCollection, "Collections"),
+		fmt.Sprintf("Bookmarks captured: %d", len(m.bookmarks)),
+		fmt.Sprintf("Possibly shifted: %d", m.shiftedWorks),
 		fmt.Sprintf("To crawl: %d", m.queue.Len()),
+		fmt.Sprintf("Retrying: %d", len(m.failures)),
+		fmt.Sprintf("Gave up on: %d", len(m.deadLetters)),
 		fmt.Sprintf("Crawled: %d", m.pagesCrawled),
 		fmt.Sprintf("Total pages: %d", totalPages),
 	}
 
+	if len(m.unrecognizedPages) > 0 {
+		stats = append(stats, lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("Unrecognized pages: %d (see log)", len(m.unrecognizedPages))))
+	}
+
+	if hasBudget {
+		stats = append(stats, fmt.Sprintf("Budget left: %s this hour, %s today", budgetString(budgetHour), budgetString(budgetDay)))
+	}
+
+	// list the most recent pages given up on
+	if len(m.deadLetters) > 0 {
+		stats = append(stats, "", "Gave up on:")
+
+		for _, deadLetter := range m.deadLetters[max(len(m.deadLetters)-maxDeadLettersShown, 0):] {
+			stats = append(stats, "  "+deadLetter.URL)
+		}
+	}
+
 	// render all the stats to a block of text
 	statBlock := lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
@@ -227,6 +324,7 @@ func (m RuntimeModel) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "esc", "ctrl+c":
+			m.cancel()
 			return m, tea.Quit
 		}
 
@@ -252,52 +350,114 @@ func (m RuntimeModel) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// sleep time over, crawl
-		if m.nextCrawlTime.Compare(time.Now()) == -1 {
-			toCrawl := m.queue.PopFront()
+		if !time.Now().Before(m.client.Scheduler().Next()) {
+			toCrawl, ok := m.popReady()
+			m.waitingToRetry = !ok
+			if !ok {
+				return m, tick()
+			}
+
 			m.crawlInProgress = true
+			m.inFlight = toCrawl
 
 			return m, tea.Batch(
 				tick(),
-				startCrawl(m.client, toCrawl, m.includeSeries),
+				startCrawl(m.ctx, m.client, toCrawl),
 			)
 		}
 
 		return m, tick()
 	case crawlResponseMsg:
+		if msg.Canceled {
+			return m, nil
+		}
+
+		m.crawlInProgress = false
+		m.checkHost()
+
 		if msg.Fatal {
+			m.queue.PushFront(msg.CrawlUrl)
+			m.logger.Printf("%s Stopping.\n  for %s", msg.ErrMsg, msg.CrawlUrl)
+			m.cancel()
 			return m, tea.Quit
 		}
 
-		m.crawlInProgress = false
+		if msg.BudgetSpent {
+			m.queue.PushFront(msg.CrawlUrl)
+			m.logger.Println("Request budget spent; stopping.")
+			m.cancel()
+			return m, tea.Quit
+		}
 
 		if msg.Success {
 			m.pagesCrawled++
+			m.recordSuccess(msg.CrawlUrl)
 
 			m.workSet.Append(msg.AddWorks...)
 
-			for _, crawlable := range msg.AddSeries {
-				m.seriesSet.Add(crawlable)
-				m.queueUrl(crawlable)
+			crawlUrl, _ := url.Parse(msg.CrawlUrl)
+			path := m.pathOf(msg.CrawlUrl)
+
+			for _, work := range msg.AddWorks {
+				m.setPath(work, path)
+
+				if m.discovery.visitsWorks() {
+					m.visitWork(work)
+				}
+			}
+
+			for _, bookmark := range msg.AddBookmarks {
+				m.bookmarks[bookmarkKey(bookmark)] = bookmark
+				m.setPath(bookmark.Item, path)
+			}
+
+			for _, blurb := range msg.AddBlurbs {
+				m.graph.AddWork(blurb)
 			}
 
-			if msg.LastDetectedPage != 0 && (m.autodetectStop || isSeriesMatcher.MatchString(msg.CrawlUrl)) {
-				crawlUrl, _ := url.Parse(msg.CrawlUrl)
-				m.queueUrlRange(*crawlUrl, msg.LastDetectedPage)
+			if msg.Work != nil {
+				m.graph.AddWorkPage(*msg.Work)
 			}
 
-			m.currentDelay = time.Duration(delayDecayFactor * float32(max(m.delay, m.currentDelay)))
+			for _, series := range msg.AddSeries {
+				m.discover(EdgeSeries, series, msg.CrawlUrl)
+			}
+
+			for _, author := range msg.AddAuthors {
+				m.discover(EdgeAuthor, author, msg.CrawlUrl)
+			}
+
+			for _, related := range msg.AddRelated {
+				m.discover(EdgeRelated, related, msg.CrawlUrl)
+			}
+
+			for _, collection := range msg.AddCollections {
+				m.discover(EdgeCollection, collection, msg.CrawlUrl)
+			}
+
+			if !isWorkPage(msg.CrawlUrl) {
+				m.checkSelectors(msg)
+				m.checkDrift(*crawlUrl, msg.AddWorks, msg.ResultCount, msg.LastDetectedPage)
+			}
+
+			if msg.LastDetectedPage != 0 && (m.autodetectStop || isSeriesMatcher.MatchString(msg.CrawlUrl) || m.wasDiscovered(msg.CrawlUrl)) {
+				if !m.splitPartition(*crawlUrl, msg.LastDetectedPage) {
+					m.queueUrlRange(*crawlUrl, msg.LastDetectedPage)
+				}
+			}
 		} else {
 			logmsg := msg.ErrMsg
+			wait := time.Second * time.Duration(msg.WaitFor)
 
-			if msg.WaitFor > 0 {
-				wait := time.Second * time.Duration(msg.WaitFor)
-
+			if wait > 0 {
 				logmsg += fmt.Sprintf(" [server-requested delay: %s]", wait.String())
 			}
 
-			if msg.Retryable {
-				m.queue.PushBack(msg.CrawlUrl)
+			// a page the server keeps asking to wait for is still given up on eventually
+			if m.recordFailure(msg.CrawlUrl, msg.ErrMsg, msg.Retryable, wait) {
 				logmsg += " [will retry]"
+			} else if msg.Retryable {
+				logmsg += " [giving up]"
 			} else {
 				logmsg += " [unretryable]"
 			}
@@ -305,12 +465,8 @@ func (m RuntimeModel) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 			logmsg += "\n  for " + msg.CrawlUrl
 
 			m.logger.Println(logmsg)
-
-			m.currentDelay = time.Duration(float32(max(m.delay, m.currentDelay)) * delayBackoffFactor)
 		}
 
-		m.nextCrawlTime = time.Now().Add(max(m.currentDelay, time.Second*time.Duration(msg.WaitFor)))
-
 		return m, nil
 	}
 
@@ -325,41 +481,34 @@ func tick() tea.Cmd {
 	})
 }
 
-func startCrawl(client *ao3client.Ao3Client, crawlUrl string, includeSeries bool) tea.Cmd {
-	crawlUrlIsSeries := isSeriesMatcher.MatchString(crawlUrl)
-
+func startCrawl(ctx context.Context, client *ao3client.Ao3Client, crawlUrl string) tea.Cmd {
 	return func() tea.Msg {
-		return crawl(client, crawlUrl, includeSeries && !crawlUrlIsSeries)
+		return crawl(ctx, client, crawlUrl)
 	}
 }
 
 // region other functions
 
-func crawl(client *ao3client.Ao3Client, crawlUrl string, includeSeries bool) (cr crawlResponseMsg) {
+func crawl(ctx context.Context, client *ao3client.Ao3Client, crawlUrl string) (cr crawlResponseMsg) {
 	cr.CrawlUrl = crawlUrl
 
+	requestUrl := crawlUrl
+	if isWorkPage(crawlUrl) {
+		requestUrl += "?view_adult=true"
+	}
+
 	// make request, handle errors
-	resp, err := client.Get(crawlUrl)
+	resp, err := client.Get(ctx, requestUrl)
 	if err != nil {
-		err := err.(*url.Error)
-
-		if err.Timeout() {
-			cr.Retryable = true
-			cr.ErrMsg = "Request timed out."
-			return
-		}
-
-		cr.ErrMsg = fmt.Sprint("Unknown error: ", err.Error())
+		classifyRequestError(err, &cr)
 		return
 	}
 	defer resp.Body.Close()
 
-	// handle retry header
+	// handle retry header; the client's scheduler has already paused for it
 	if retryHeader := resp.Header.Get("Retry-After"); retryHeader != "" {
-		if retryTime, err := strconv.Atoi(retryHeader); err == nil {
-			cr.WaitFor = retryTime
-		} else if retryDate, err := http.ParseTime(retryHeader); err == nil {
-			cr.WaitFor = int(time.Until(retryDate).Seconds())
+		if wait, err := scheduler.ParseRetryAfter(retryHeader, time.Now()); err == nil {
+			cr.WaitFor = int(wait.Seconds())
 		} else {
 			cr.ErrMsg = fmt.Sprintf("Server requested pause, but gave invalid time ('%s').", retryHeader)
 			cr.Fatal = true
@@ -397,21 +546,55 @@ func crawl(client *ao3client.Ao3Client, crawlUrl string, includeSeries bool) (cr
 		return
 	}
 
-	for _, node := range cascadia.QueryAll(dom, workSelector) {
+	if isWorkPage(crawlUrl) {
+		crawlWorkPage(client, dom, crawlUrl, &cr)
+		return
+	}
+
+	crawlListingPage(client, dom, &cr)
+	return
+}
+
+// crawlListingPage collects the works, bookmarks, series, and authors on a
+// listing page, and how long the listing is.
+func crawlListingPage(client *ao3client.Ao3Client, dom *html.Node, cr *crawlResponseMsg) {
+	selectors := client.Profile().Listing
+
+	for _, node := range cascadia.QueryAll(dom, selectors.WorkLink) {
 		href, _ := getHref(node)
 
 		cr.AddWorks = append(cr.AddWorks, client.ToFullURL(href))
 	}
 
-	if includeSeries {
-		for _, series := range cascadia.QueryAll(dom, seriesSelector) {
-			href, _ := getHref(series)
+	for _, blurb := range cascadia.QueryAll(dom, selectors.BookmarkBlurb) {
+		if bookmark, ok := client.ParseBookmarkBlurb(blurb); ok {
+			cr.AddBookmarks = append(cr.AddBookmarks, bookmark)
+
+			if bookmark.Work != nil {
+				cr.AddBlurbs = append(cr.AddBlurbs, *bookmark.Work)
+			}
+		}
+	}
 
-			cr.AddSeries = append(cr.AddSeries, client.ToFullURL(href))
+	for _, blurb := range cascadia.QueryAll(dom, selectors.WorkBlurb) {
+		if work, ok := client.ParseWorkBlurb(blurb); ok {
+			cr.AddBlurbs = append(cr.AddBlurbs, work)
 		}
 	}
 
-	lastPage := cascadia.Query(dom, paginationSelector)
+	for _, series := range cascadia.QueryAll(dom, selectors.SeriesLink) {
+		href, _ := getHref(series)
+
+		cr.AddSeries = append(cr.AddSeries, client.ToFullURL(href))
+	}
+
+	for _, author := range cascadia.QueryAll(dom, selectors.AuthorLink) {
+		href, _ := getHref(author)
+
+		cr.AddAuthors = append(cr.AddAuthors, client.ToFullURL(href)+"/works")
+	}
+
+	lastPage := cascadia.Query(dom, selectors.LastPage)
 	if lastPage != nil {
 		href, _ := getHref(lastPage)
 
@@ -420,18 +603,87 @@ func crawl(client *ao3client.Ao3Client, crawlUrl string, includeSeries bool) (cr
 		cr.LastDetectedPage = getPageNum(*u)
 	}
 
+	if heading := cascadia.Query(dom, selectors.ResultCount); heading != nil {
+		cr.ResultCount = parseResultCount(getText(heading))
+	}
+
 	cr.Success = true
-	return
 }
 
-func mustParseSelector(selector string) cascadia.Matcher {
-	sel, err := cascadia.ParseGroup(selector)
+// crawlWorkPage collects the links on a work's own page that lead to other
+// works, series, authors, and collections.
+func crawlWorkPage(client *ao3client.Ao3Client, dom *html.Node, crawlUrl string, cr *crawlResponseMsg) {
+	// restricted works redirect to the login page, which has nothing to follow
+	cr.Success = true
+
+	work, ok := client.ParseWork(dom, crawlUrl)
+	if !ok {
+		return
+	}
 
-	if err != nil {
-		panic(err)
+	cr.Work = &work
+
+	for _, series := range work.Series {
+		cr.AddSeries = append(cr.AddSeries, series.URL)
 	}
 
-	return sel
+	for _, author := range work.Authors {
+		cr.AddAuthors = append(cr.AddAuthors, author.URL+"/works")
+	}
+
+	for _, related := range slices.Concat(work.InspiredBy, work.Inspired, work.TranslationOf, work.Translations) {
+		if u, err := url.Parse(related); err == nil && isWorkMatcher.MatchString(u.Path) {
+			cr.AddRelated = append(cr.AddRelated, related)
+		}
+	}
+
+	for _, collection := range work.Collections {
+		cr.AddCollections = append(cr.AddCollections, collection+"/works")
+	}
+}
+
+// classifyRequestError describes an error from making a request, and decides
+// whether the request is worth retrying.
+func classifyRequestError(err error, cr *crawlResponseMsg) {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var proxyErr *ao3client.ProxyError
+	var unavailable *ao3client.UnavailableError
+
+	switch {
+	case errors.Is(err, scheduler.ErrBudgetSpent):
+		cr.BudgetSpent = true
+		cr.ErrMsg = "Request budget spent."
+	case errors.Is(err, context.Canceled):
+		cr.Canceled = true
+		cr.ErrMsg = "Request cancelled."
+	case errors.Is(err, ao3client.ErrNotCached):
+		cr.ErrMsg = "Not in cache."
+	case errors.Is(err, ao3client.ErrNotRecorded):
+		cr.ErrMsg = "Not in recording."
+	case errors.As(err, &unavailable):
+		// not an empty page, which could end a crawl early
+		cr.Retryable = true
+		cr.WaitFor = int(unavailable.Wait.Seconds())
+		cr.ErrMsg = unavailable.Error() + "."
+	case errors.As(err, &proxyErr):
+		// the proxy won't start accepting the credentials on its own
+		cr.Fatal = errors.Is(err, ao3client.ErrProxyAuth)
+		cr.Retryable = !cr.Fatal
+		cr.ErrMsg = fmt.Sprint("Proxy error, not from the site: ", proxyErr.Error())
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		cr.Retryable = true
+		cr.ErrMsg = "Request timed out."
+	case errors.As(err, &dnsErr):
+		cr.Retryable = dnsErr.IsTemporary
+		cr.ErrMsg = fmt.Sprint("Couldn't look up host: ", dnsErr.Name)
+	case errors.As(err, &opErr):
+		cr.Retryable = true
+		cr.ErrMsg = fmt.Sprint("Network error: ", opErr.Err)
+	default:
+		cr.ErrMsg = fmt.Sprint("Unknown error: ", err.Error())
+	}
 }
 
 func getHref(t *html.Node) (string, error) {
@@ -443,6 +695,26 @@ func getHref(t *html.Node) (string, error) {
 	return "", errors.New("no href attribute found")
 }
 
+func getText(t *html.Node) string {
+	if t.Type == html.TextNode {
+		return t.Data
+	}
+
+	text := ""
+	for c := t.FirstChild; c != nil; c = c.NextSibling {
+		text += getText(c)
+	}
+	return text
+}
+
+func budgetString(remaining int) string {
+	if remaining < 0 {
+		return "no limit"
+	}
+
+	return strconv.Itoa(remaining)
+}
+
 func remainingLines(m *RuntimeModel, doc *strings.Builder) int {
 	return m.height - strings.Count(doc.String(), "\n") - 1
 }
@@ -488,6 +760,29 @@ func (m *RuntimeModel) queueUrlRange(seedURL url.URL, endPage int) {
 	}
 }
 
+// splitPartition replaces an oversized partition with its two halves, if it
+// can be split. It only acts on a partition's first page, before the rest of
+// its pages are queued.
+func (m *RuntimeModel) splitPartition(partition url.URL, lastPage int) bool {
+	if m.partitionMaxPages <= 0 || lastPage <= m.partitionMaxPages || getPageNum(partition) != 1 {
+		return false
+	}
+
+	halves, ok := search.Split(partition)
+	if !ok {
+		return false
+	}
+
+	m.logger.Printf("Partition has %d pages, splitting in two.\n  for %s", lastPage, partition.String())
+
+	for _, half := range halves {
+		m.setPath(half.String(), m.pathOf(partition.String()))
+		m.queueUrl(half.String())
+	}
+
+	return true
+}
+
 func (m *RuntimeModel) GetWorkCount() int {
 	return m.workSet.Cardinality()
 }
@@ -496,6 +791,64 @@ func (m *RuntimeModel) GetPagesCrawled() int {
 	return m.pagesCrawled
 }
 
+func (m *RuntimeModel) GetShiftedWorkCount() int {
+	return m.shiftedWorks
+}
+
 func (m *RuntimeModel) GetWorks() <-chan string {
 	return m.workSet.Iter()
 }
+
+// GetRemaining returns the URLs still queued to be crawled, if the crawl was
+// stopped early.
+func (m *RuntimeModel) GetRemaining() []string {
+	remaining := make([]string, 0, m.queue.Len()+1)
+	if m.crawlInProgress {
+		remaining = append(remaining, m.inFlight)
+	}
+
+	for i := range m.queue.Len() {
+		remaining = append(remaining, m.queue.At(i))
+	}
+
+	return remaining
+}
+
+func (m *RuntimeModel) GetBookmarkCount() int {
+	return len(m.bookmarks)
+}
+
+// GetGraph returns the relationships seen between works, series, and creators.
+func (m *RuntimeModel) GetGraph() *graph.Graph {
+	return m.graph
+}
+
+// GetRecords returns every discovered work, plus any other bookmarked items,
+// with their bookmarks attached. Records are sorted by URL.
+func (m *RuntimeModel) GetRecords() []Record {
+	records := make(map[string]*Record)
+
+	for work := range m.workSet.Iter() {
+		records[work] = &Record{URL: work, Via: m.pathOf(work)}
+	}
+
+	for _, bookmark := range m.bookmarks {
+		record, ok := records[bookmark.Item]
+		if !ok {
+			record = &Record{URL: bookmark.Item, Via: m.pathOf(bookmark.Item)}
+			records[bookmark.Item] = record
+		}
+
+		record.Bookmarks = append(record.Bookmarks, bookmark)
+	}
+
+	result := make([]Record, 0, len(records))
+	for _, record := range records {
+		slices.SortFunc(record.Bookmarks, func(a, b ao3client.Bookmark) int { return strings.Compare(bookmarkKey(a), bookmarkKey(b)) })
+		result = append(result, *record)
+	}
+
+	slices.SortFunc(result, func(a, b Record) int { return strings.Compare(a.URL, b.URL) })
+
+	return result
+}
@@ -1,15 +1,25 @@
 package crawler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/ao3client"
+	"github.com/legowerewolf/AO3fetch/seeds"
 )
 
 func initTestingRuntimeModel() (m RuntimeModel) {
 	u, _ := url.Parse("https://archiveofourown.org")
 
-	return InitRuntimeModel(false, 0, *u, 0, nil)
+	return InitRuntimeModel(Config{}, []seeds.Seed{{URL: *u, Kind: seeds.TagWorks}}, nil)
 }
 
 func TestQueueUrlRepeatedly(t *testing.T) {
@@ -57,3 +67,165 @@ func FuzzQueueUrlRange(f *testing.F) {
 		}
 	})
 }
+
+func TestDriftRequeuesBoundaryPage(t *testing.T) {
+	m := initTestingRuntimeModel()
+
+	page := func(n int) url.URL {
+		u, _ := url.Parse("https://TestDrift.com/works?page=" + strconv.Itoa(n))
+		return *u
+	}
+
+	m.checkDrift(page(3), []string{"a", "b", "c"}, 60, 3)
+
+	initial := m.queue.Len()
+
+	// "c" was pushed from page 3 back to page 2 between crawls
+	m.checkDrift(page(2), []string{"x", "y", "c"}, 59, 3)
+
+	if m.queue.Len() != initial+1 || m.queue.Back() != withPage(page(2), 3) {
+		t.Error("expected page 3 to be requeued")
+	}
+
+	if m.GetShiftedWorkCount() != 1 {
+		t.Errorf("expected 1 shifted work, got %d", m.GetShiftedWorkCount())
+	}
+
+	// requeues are capped so a busy listing can't loop forever
+	for range maxRequeuesPerPage + 1 {
+		m.checkDrift(page(2), []string{"x", "y", "c"}, 59, 3)
+	}
+
+	if m.queue.Len() != initial+maxRequeuesPerPage {
+		t.Errorf("expected requeues to stop at %d", maxRequeuesPerPage)
+	}
+}
+
+func TestParseResultCount(t *testing.T) {
+	headings := map[string]int{
+		"1 - 20 of 12,345 Works in Fluff": 12345,
+		"3 Works in Some Tag":             3,
+		"21 - 40 of 95 Bookmarks by me":   95,
+		"Some Series Title":               0,
+	}
+
+	for heading, expected := range headings {
+		if got := parseResultCount(heading); got != expected {
+			t.Errorf("%q: expected %d, got %d", heading, expected, got)
+		}
+	}
+}
+
+func TestDiscoveryDepth(t *testing.T) {
+	seed, _ := url.Parse("https://archiveofourown.org/tags/Test/works")
+	first := "https://archiveofourown.org/series/1"
+	second := "https://archiveofourown.org/series/2"
+
+	for depth, wantSecond := range map[int]bool{1: false, 2: true} {
+		config := Config{Pages: 1, Discovery: Discovery{Series: Rule{Enabled: true, Depth: depth}}}
+		m := InitRuntimeModel(config, []seeds.Seed{{URL: *seed, Kind: seeds.TagWorks}}, nil)
+
+		m.discover(EdgeSeries, first, seed.String())
+		m.discover(EdgeSeries, second, first)
+
+		if !m.queueSet.Contains(first) {
+			t.Errorf("depth %d: series found on seed wasn't queued", depth)
+		}
+
+		if m.queueSet.Contains(second) != wantSecond {
+			t.Errorf("depth %d: series found on series queued = %t, want %t", depth, !wantSecond, wantSecond)
+		}
+	}
+}
+
+func TestDiscoveryPath(t *testing.T) {
+	seed, _ := url.Parse("https://archiveofourown.org/works/1")
+	related := "https://archiveofourown.org/works/2"
+	collection := "https://archiveofourown.org/collections/test/works"
+
+	config := Config{Pages: 1, Discovery: Discovery{
+		Related:     Rule{Enabled: true, Depth: 1},
+		Collections: Rule{Enabled: true, Depth: 1},
+	}}
+	m := InitRuntimeModel(config, []seeds.Seed{{URL: *seed, Kind: seeds.Work}}, nil)
+
+	if !m.queueSet.Contains(seed.String()) {
+		t.Fatal("seed work's page wasn't queued")
+	}
+
+	m.discover(EdgeRelated, related, seed.String())
+	m.discover(EdgeCollection, collection, related)
+
+	if !m.workSet.Contains(related) {
+		t.Error("related work wasn't collected")
+	}
+
+	path := m.pathOf(collection + "?page=2")
+	want := Path{{EdgeSeed, seed.String()}, {EdgeRelated, related}, {EdgeCollection, collection}}
+
+	if !slices.Equal(path, want) {
+		t.Errorf("got path %v, want %v", path, want)
+	}
+}
+
+func TestRetryLimit(t *testing.T) {
+	m := InitRuntimeModel(Config{Retry: RetryPolicy{MaxAttempts: 2, Backoff: time.Hour, Factor: 2}}, nil, nil)
+
+	failing := "https://TestRetryLimit.com/works?page=1"
+	m.queueUrl(failing)
+	m.queueUrl("https://TestRetryLimit.com/works?page=2")
+
+	crawlUrl, _ := m.popReady()
+	if !m.recordFailure(crawlUrl, "Server error (503).", true, 0) {
+		t.Fatal("expected first failure to be retried")
+	}
+
+	// the failed page is held back, so the other page comes first
+	if next, _ := m.popReady(); next == failing {
+		t.Error("failed page wasn't held back")
+	}
+
+	if _, ok := m.popReady(); ok {
+		t.Error("expected nothing to be ready while the failed page is held back")
+	}
+
+	if m.recordFailure(failing, "Server error (503).", true, 0) {
+		t.Fatal("expected second failure to give up")
+	}
+
+	deadLetters := m.GetDeadLetters()
+	if len(deadLetters) != 1 || len(deadLetters[0].Errors) != 2 {
+		t.Fatalf("expected one dead letter with two errors, got %v", deadLetters)
+	}
+
+	buf := strings.Builder{}
+	WriteDeadLetters(&buf, deadLetters)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "# ") || lines[2] != failing {
+		t.Errorf("unexpected failures file:\n%s", buf.String())
+	}
+}
+
+func TestClassifyRequestError(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+		canceled  bool
+	}{
+		{fmt.Errorf("Request failed: %w", context.DeadlineExceeded), true, false},
+		{fmt.Errorf("Request failed: %w", context.Canceled), false, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true, false},
+		{fmt.Errorf("Request failed: %w", &ao3client.UnavailableError{Reason: "down for maintenance"}), true, false},
+		{errors.New("something else"), false, false},
+	}
+
+	for _, c := range cases {
+		cr := crawlResponseMsg{}
+		classifyRequestError(c.err, &cr)
+
+		if cr.Retryable != c.retryable || cr.Canceled != c.canceled {
+			t.Errorf("%v: expected retryable %t, canceled %t; got %t, %t", c.err, c.retryable, c.canceled, cr.Retryable, cr.Canceled)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// region consts
+
+var isWorkMatcher = regexp.MustCompile(`^/works/\d+$`)
+
+// region discovery graph
+
+// Edge is a way one page can lead to another while a crawl spreads out from
+// its seeds.
+type Edge string
+
+const (
+	EdgeSeed       Edge = "seed"       // a starting URL
+	EdgeSeries     Edge = "series"     // a series a work belongs to
+	EdgeAuthor     Edge = "author"     // a work's author's works listing
+	EdgeRelated    Edge = "related"    // a work inspired by, or inspiring, another
+	EdgeCollection Edge = "collection" // a collection a work is in
+)
+
+// Rule controls how far a crawl follows one kind of edge.
+type Rule struct {
+	Enabled bool
+	Depth   int // how many edges of this kind a path may contain
+	Max     int // how many items may be discovered this way; 0 for no limit
+}
+
+// Discovery holds the rules for every kind of edge a crawl can follow.
+type Discovery struct {
+	Series      Rule
+	Authors     Rule
+	Related     Rule
+	Collections Rule
+}
+
+func (d Discovery) rule(edge Edge) Rule {
+	switch edge {
+	case EdgeSeries:
+		return d.Series
+	case EdgeAuthor:
+		return d.Authors
+	case EdgeRelated:
+		return d.Related
+	case EdgeCollection:
+		return d.Collections
+	}
+
+	return Rule{}
+}
+
+// visitsWorks reports whether work pages need to be fetched, which is only
+// the case when following edges that blurbs don't show.
+func (d Discovery) visitsWorks() bool {
+	return d.Related.Enabled || d.Collections.Enabled
+}
+
+// Step is one edge on the path by which an item was discovered.
+type Step struct {
+	Edge Edge   `json:"edge"`
+	URL  string `json:"url"`
+}
+
+// Path is the chain of edges leading from a seed to an item.
+type Path []Step
+
+func (p Path) count(edge Edge) int {
+	n := 0
+	for _, step := range p {
+		if step.Edge == edge {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (p Path) extend(edge Edge, target string) Path {
+	return append(p[:len(p):len(p)], Step{Edge: edge, URL: target})
+}
+
+// allows reports whether an item at the end of this path may be followed
+// further along an edge.
+func (p Path) allows(rule Rule, edge Edge) bool {
+	return rule.Enabled && p.count(edge) < rule.Depth
+}
+
+// region discovery
+
+// discover follows an edge from the page at from to target, unless the rules
+// for that kind of edge forbid it. Listings are queued to be crawled; works
+// are collected, and their pages queued if anything more can be found there.
+func (m *RuntimeModel) discover(edge Edge, target string, from string) {
+	rule := m.discovery.rule(edge)
+	path := m.pathOf(from)
+
+	if !path.allows(rule, edge) || m.discovered[edge].Contains(target) {
+		return
+	}
+
+	if edge == EdgeAuthor && strings.Contains(target, "/users/"+orphanAccount+"/") {
+		return
+	}
+
+	if rule.Max > 0 && m.discovered[edge].Cardinality() >= rule.Max {
+		if !m.limitReached[edge] {
+			m.logger.Printf("Reached the limit of %d items discovered by %s; no more will be followed.", rule.Max, edge)
+			m.limitReached[edge] = true
+		}
+
+		return
+	}
+
+	m.discovered[edge].Add(target)
+	m.setPath(target, path.extend(edge, target))
+
+	if edge == EdgeRelated {
+		m.workSet.Add(target)
+		m.visitWork(target)
+		return
+	}
+
+	m.queueUrl(target)
+}
+
+// visitWork queues a work's page to be crawled, if the work's path still
+// allows following edges that only work pages show.
+func (m *RuntimeModel) visitWork(work string) {
+	path := m.pathOf(work)
+
+	if path.allows(m.discovery.Related, EdgeRelated) || path.allows(m.discovery.Collections, EdgeCollection) {
+		m.queueUrl(work)
+	}
+}
+
+// pathOf returns the path by which the listing or work a URL belongs to was
+// discovered.
+func (m *RuntimeModel) pathOf(crawlUrl string) Path {
+	return m.paths[pathKey(crawlUrl)]
+}
+
+// setPath records the path to an item, unless a path to it is already known.
+func (m *RuntimeModel) setPath(target string, path Path) {
+	key := pathKey(target)
+
+	if _, ok := m.paths[key]; !ok {
+		m.paths[key] = path
+	}
+}
+
+func (m *RuntimeModel) wasDiscovered(crawlUrl string) bool {
+	path := m.pathOf(crawlUrl)
+
+	return len(path) > 0 && path[len(path)-1].Edge != EdgeSeed
+}
+
+func pathKey(crawlUrl string) string {
+	u, err := url.Parse(crawlUrl)
+	if err != nil {
+		return crawlUrl
+	}
+
+	return listingKey(*u)
+}
+
+func isWorkPage(crawlUrl string) bool {
+	u, err := url.Parse(crawlUrl)
+
+	return err == nil && isWorkMatcher.MatchString(u.Path)
+}
+
+func newDiscoveredSets() map[Edge]mapset.Set[string] {
+	return map[Edge]mapset.Set[string]{
+		EdgeSeries:     mapset.NewSet[string](),
+		EdgeAuthor:     mapset.NewSet[string](),
+		EdgeRelated:    mapset.NewSet[string](),
+		EdgeCollection: mapset.NewSet[string](),
+	}
+}
@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// region consts
+
+const listingPageSize = 20 // items AO3 shows per listing page
+const maxRequeuesPerPage = 2
+
+var resultCountMatcher = regexp.MustCompile(`([\d,]+) (?:Works|Bookmarks|Series)`)
+
+// region drift tracking
+
+// listingState is what's been seen of one paginated listing, across all of its
+// pages, so that items moving between pages mid-crawl can be noticed.
+type listingState struct {
+	resultCount int              // most recently reported number of results
+	lastPage    int              // most recently detected last page
+	pages       map[int][]string // works seen on each page
+	requeues    map[int]int      // times each page has been re-queued
+}
+
+// checkDrift records a crawled listing page and compares it against the pages
+// around it. If items have shifted across page boundaries since those pages
+// were crawled, the affected neighbours are queued to be crawled again.
+func (m *RuntimeModel) checkDrift(crawlUrl url.URL, works []string, resultCount int, lastPage int) {
+	key, pageNum := listingKey(crawlUrl), getPageNum(crawlUrl)
+
+	state, ok := m.listings[key]
+	if !ok {
+		state = &listingState{pages: make(map[int][]string), requeues: make(map[int]int)}
+		m.listings[key] = state
+	}
+
+	shifted := 0
+
+	// the same work on two adjacent pages means the listing moved between crawls
+	for _, neighbour := range []int{pageNum - 1, pageNum + 1} {
+		if seen, ok := state.pages[neighbour]; ok {
+			shifted = max(shifted, mapset.NewSet(seen...).Intersect(mapset.NewSet(works...)).Cardinality())
+		}
+	}
+
+	// a changed result count or last page means works were added or removed
+	if resultCount > 0 && state.resultCount > 0 && resultCount != state.resultCount {
+		shifted = max(shifted, abs(resultCount-state.resultCount))
+	} else if resultCount <= 0 && lastPage > 0 && state.lastPage > 0 && lastPage != state.lastPage {
+		shifted = max(shifted, abs(lastPage-state.lastPage)*listingPageSize)
+	}
+
+	if lastPage > 0 && state.lastPage > 0 && lastPage < state.lastPage {
+		m.logger.Printf("Listing shrank from %d to %d pages during the crawl.\n  for %s", state.lastPage, lastPage, key)
+	}
+
+	state.pages[pageNum] = works
+	if resultCount > 0 {
+		state.resultCount = resultCount
+	}
+	if lastPage > 0 {
+		state.lastPage = lastPage
+	}
+
+	if shifted == 0 {
+		return
+	}
+
+	m.shiftedWorks += shifted
+
+	// works can only be missed at the edges of pages crawled before the shift,
+	// so recrawl enough of those to cover how far things moved
+	reach := (shifted + listingPageSize - 1) / listingPageSize
+
+	requeued := []string{}
+	for distance := 1; distance <= reach; distance++ {
+		for _, neighbour := range []int{pageNum - distance, pageNum + distance} {
+			if _, crawled := state.pages[neighbour]; !crawled || state.requeues[neighbour] >= maxRequeuesPerPage {
+				continue
+			}
+
+			state.requeues[neighbour]++
+			m.queue.PushBack(withPage(crawlUrl, neighbour))
+			requeued = append(requeued, strconv.Itoa(neighbour))
+		}
+	}
+
+	logmsg := "Detected pagination drift: up to " + strconv.Itoa(shifted) + " works shifted."
+	if len(requeued) > 0 {
+		logmsg += " [recrawling page " + strings.Join(requeued, ", ") + "]"
+	}
+	m.logger.Println(logmsg + "\n  for " + crawlUrl.String())
+}
+
+// region helpers
+
+// listingKey identifies a listing independently of which page of it a URL
+// points to.
+func listingKey(u url.URL) string {
+	query := u.Query()
+	query.Del("page")
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func withPage(u url.URL, pageNum int) string {
+	query := u.Query()
+	query.Set("page", strconv.Itoa(pageNum))
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func parseResultCount(heading string) int {
+	match := resultCountMatcher.FindStringSubmatch(heading)
+	if match == nil {
+		return 0
+	}
+
+	count, err := strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
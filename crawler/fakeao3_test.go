@@ -0,0 +1,163 @@
+package crawler
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/ao3client"
+	"github.com/legowerewolf/AO3fetch/fakeao3"
+	"github.com/legowerewolf/AO3fetch/scheduler"
+	"github.com/legowerewolf/AO3fetch/seeds"
+)
+
+// runCrawl drives a crawl to the end the way the bubbletea program would, but
+// without its timers.
+func runCrawl(t *testing.T, m RuntimeModel, clock *fakeao3.Clock) RuntimeModel {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for m.queue.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("crawl didn't finish; still queued: %v", m.GetRemaining())
+		}
+
+		toCrawl, ok := m.popReady()
+		if !ok {
+			// everything queued is being held back; skip ahead
+			clock.Sleep(m.ctx, time.Minute)
+			continue
+		}
+
+		m.crawlInProgress = true
+		m.inFlight = toCrawl
+
+		model, _ := m.Update(crawl(m.ctx, m.client, toCrawl))
+		m = model.(RuntimeModel)
+	}
+
+	return m
+}
+
+func newFakeSiteCrawl(t *testing.T, site *fakeao3.Server, config Config, seedURL string) (RuntimeModel, *fakeao3.Clock) {
+	clock := fakeao3.NewClock()
+
+	client, err := ao3client.NewAo3Client(site.URL, ao3client.Options{
+		Scheduler: scheduler.New(scheduler.Config{Clock: clock}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(seedURL)
+	seed, err := seeds.Classify(*u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return InitRuntimeModel(config, []seeds.Seed{seed}, client), clock
+}
+
+func TestFakeSiteCrawl(t *testing.T) {
+	site := fakeao3.New()
+	defer site.Close()
+
+	for id := 1; id <= 45; id++ {
+		site.AddWork(fakeao3.Work{ID: id, Title: "Work " + strconv.Itoa(id), Author: "writer", Tags: []string{"Tag"}})
+	}
+
+	// only found through the series
+	site.AddWork(fakeao3.Work{ID: 100, Title: "Untagged", Author: "writer"})
+	site.AddSeries(fakeao3.Series{ID: 1, Title: "A Series", Author: "writer", Works: []int{1, 100}})
+
+	site.Fail("/tags/Tag/works", fakeao3.ServerError(503), fakeao3.RetryAfter(30))
+
+	m, clock := newFakeSiteCrawl(t, site, Config{
+		Discovery: Discovery{Series: Rule{Enabled: true, Depth: 1}},
+		Retry:     RetryPolicy{MaxAttempts: 3, Factor: 1},
+	}, site.URL+"/tags/Tag/works")
+
+	m = runCrawl(t, m, clock)
+
+	if m.workSet.Cardinality() != 46 {
+		t.Errorf("expected 46 works, found %d", m.workSet.Cardinality())
+	}
+
+	if !m.workSet.Contains(site.URL + "/works/100") {
+		t.Error("expected the work found through its series")
+	}
+
+	if len(m.GetDeadLetters()) != 0 {
+		t.Errorf("expected the failing page to be retried until it worked, gave up on %v", m.GetDeadLetters())
+	}
+}
+
+func TestFakeSiteCrawlGivesUp(t *testing.T) {
+	site := fakeao3.New()
+	defer site.Close()
+
+	site.AddWork(fakeao3.Work{ID: 1, Title: "Work", Author: "writer", Tags: []string{"Tag"}})
+	site.Fail("/tags/Tag/works", fakeao3.ServerError(502), fakeao3.ServerError(502))
+
+	m, clock := newFakeSiteCrawl(t, site, Config{Pages: 1, Retry: RetryPolicy{MaxAttempts: 2, Factor: 1}}, site.URL+"/tags/Tag/works")
+	m = runCrawl(t, m, clock)
+
+	if deadLetters := m.GetDeadLetters(); len(deadLetters) != 1 || len(deadLetters[0].Errors) != 2 {
+		t.Errorf("expected the page to be given up on after two attempts, got %v", deadLetters)
+	}
+}
+
+func TestFakeSiteCrawlMaintenance(t *testing.T) {
+	site := fakeao3.New()
+	defer site.Close()
+
+	for id := 1; id <= 45; id++ {
+		site.AddWork(fakeao3.Work{ID: id, Title: "Work " + strconv.Itoa(id), Author: "writer", Tags: []string{"Tag"}})
+	}
+
+	// read as an empty page, this would end the crawl before it started
+	site.Fail("/tags/Tag/works", fakeao3.MaintenancePage(), fakeao3.RetryLaterPage())
+
+	m, clock := newFakeSiteCrawl(t, site, Config{Retry: RetryPolicy{MaxAttempts: 3, Factor: 1}}, site.URL+"/tags/Tag/works")
+	m = runCrawl(t, m, clock)
+
+	if m.workSet.Cardinality() != 45 || len(m.GetDeadLetters()) != 0 {
+		t.Errorf("expected all 45 works once the site was back, found %d and gave up on %v", m.workSet.Cardinality(), m.GetDeadLetters())
+	}
+}
+
+func TestFakeSiteCrawlGivesUpWaiting(t *testing.T) {
+	for name, fault := range map[string]fakeao3.Fault{
+		"retry after":    fakeao3.RetryAfter(30),
+		"retry later":    fakeao3.RetryLaterPage(),
+		"in maintenance": fakeao3.MaintenancePage(),
+	} {
+		site := fakeao3.New()
+		defer site.Close()
+
+		site.AddWork(fakeao3.Work{ID: 1, Title: "Work", Author: "writer", Tags: []string{"Tag"}})
+
+		// the page never comes back
+		for range 10 {
+			site.Fail("/tags/Tag/works", fault)
+		}
+
+		m, clock := newFakeSiteCrawl(t, site, Config{Pages: 1, Retry: RetryPolicy{MaxAttempts: 3, Factor: 1}}, site.URL+"/tags/Tag/works")
+		start := clock.Now()
+		m = runCrawl(t, m, clock)
+
+		if deadLetters := m.GetDeadLetters(); len(deadLetters) != 1 || len(deadLetters[0].Errors) != 3 {
+			t.Errorf("%s: expected the page to be given up on after three attempts, got %v", name, deadLetters)
+		}
+
+		if requests := len(site.Requests()); requests != 3 {
+			t.Errorf("%s: expected three requests, made %d", name, requests)
+		}
+
+		// each retry waited as long as the server asked
+		if waited := clock.Now().Sub(start); waited < 2*30*time.Second {
+			t.Errorf("%s: expected retries to wait for the server, took %s", name, waited)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/legowerewolf/AO3fetch/ao3client"
+	"golang.org/x/net/html"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/pages from the current parsers")
+
+// goldenPage is what's collected from one saved page.
+type goldenPage struct {
+	Works           []string              `json:"works,omitempty"`
+	Series          []string              `json:"series,omitempty"`
+	Authors         []string              `json:"authors,omitempty"`
+	Related         []string              `json:"related,omitempty"`
+	Collections     []string              `json:"collections,omitempty"`
+	Bookmarks       []ao3client.Bookmark  `json:"bookmarks,omitempty"`
+	Blurbs          []ao3client.WorkBlurb `json:"blurbs,omitempty"`
+	Work            *ao3client.Work       `json:"work,omitempty"`
+	LastPage        int                   `json:"lastPage,omitempty"`
+	ResultCount     int                   `json:"resultCount,omitempty"`
+	SelectorsBroken bool                  `json:"selectorsBroken,omitempty"`
+}
+
+// TestGoldenPages parses the AO3 pages in testdata/pages and compares what's
+// collected from them against the golden files beside them. Run with -update
+// after changing a parser on purpose, and check the diff. Where each page came
+// from is recorded in testdata/pages/SOURCES.md.
+func TestGoldenPages(t *testing.T) {
+	client, err := ao3client.NewAo3Client("https://archiveofourown.org", ao3client.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages, _ := filepath.Glob(filepath.Join("testdata", "pages", "*.html"))
+	if len(pages) == 0 {
+		t.Fatal("no saved pages found")
+	}
+
+	sources, err := os.ReadFile(filepath.Join("testdata", "pages", "SOURCES.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".html")
+
+		t.Run(name, func(t *testing.T) {
+			if !bytes.Contains(sources, []byte("`"+filepath.Base(page)+"`")) {
+				t.Errorf("%s isn't listed in SOURCES.md", page)
+			}
+
+			contents, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dom, err := html.Parse(bytes.NewReader(contents))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cr := crawlResponseMsg{}
+			if name == "work" {
+				crawlWorkPage(client, dom, "https://archiveofourown.org/works/12345", &cr)
+			} else {
+				crawlListingPage(client, dom, &cr)
+			}
+
+			got, err := json.MarshalIndent(goldenPage{
+				Works:           cr.AddWorks,
+				Series:          cr.AddSeries,
+				Authors:         cr.AddAuthors,
+				Related:         cr.AddRelated,
+				Collections:     cr.AddCollections,
+				Bookmarks:       cr.AddBookmarks,
+				Blurbs:          cr.AddBlurbs,
+				Work:            cr.Work,
+				LastPage:        cr.LastDetectedPage,
+				ResultCount:     cr.ResultCount,
+				SelectorsBroken: selectorsBroken(cr),
+			}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenFile := strings.TrimSuffix(page, ".html") + ".golden.json"
+
+			if *updateGolden {
+				if err := os.WriteFile(goldenFile, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatalf("no golden file; run with -update to create it: %v", err)
+			}
+
+			if !bytes.Equal(got, expected) {
+				t.Errorf("collected from %s differs from %s:\n%s", page, goldenFile, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package crawler
+
+// region markup health
+
+// selectorsBroken reports whether a listing page looks broken rather than
+// empty: its heading counts results, but none of them were found on it. That
+// means AO3's markup has changed out from under the selectors.
+func selectorsBroken(cr crawlResponseMsg) bool {
+	return cr.Success && cr.ResultCount > 0 && len(cr.AddWorks) == 0 && len(cr.AddBookmarks) == 0 && len(cr.AddSeries) == 0
+}
+
+// checkSelectors warns about a listing page whose results went unrecognized.
+func (m *RuntimeModel) checkSelectors(cr crawlResponseMsg) {
+	if !selectorsBroken(cr) {
+		return
+	}
+
+	m.unrecognizedPages = append(m.unrecognizedPages, cr.CrawlUrl)
+	m.logger.Printf("WARNING: page says it has %d results, but none were recognized. AO3's pages may have changed; please report this, with a -record recording if you can.\n  for %s", cr.ResultCount, cr.CrawlUrl)
+}
+
+// GetUnrecognizedPages returns the listing pages whose results couldn't be
+// found, which suggests AO3Fetch needs updating for AO3's current markup.
+func (m *RuntimeModel) GetUnrecognizedPages() []string {
+	return m.unrecognizedPages
+}
+
+// region failover
+
+// checkHost tells the user when requests have failed over to a mirror, and
+// that a login may not have come with them.
+func (m *RuntimeModel) checkHost() {
+	if m.client == nil {
+		return
+	}
+
+	host := m.client.Host()
+	if m.host == "" {
+		m.host = host
+	}
+	if host == m.host {
+		return
+	}
+
+	m.logger.Printf("WARNING: %s kept failing, so requests now go to %s. Output URLs are unchanged.\n", m.host, host)
+	if m.client.GetUser() != "Anonymous" {
+		m.logger.Printf("WARNING: the login was copied to %s, but may not be accepted there; restricted works may be given up on.\n", host)
+	}
+
+	m.host = host
+}
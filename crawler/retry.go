@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// region retry policy
+
+// RetryPolicy controls how often a failing URL is retried before it's given up
+// on.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts per URL, including the first
+	Backoff     time.Duration // wait before the first retry of a URL
+	Factor      float64       // growth of the wait with each further retry
+}
+
+// wait returns how long to hold a URL back after it has failed attempts times.
+func (p RetryPolicy) wait(attempts int) time.Duration {
+	return time.Duration(float64(p.Backoff) * math.Pow(p.Factor, float64(attempts-1)))
+}
+
+// failure is the error history of one URL.
+type failure struct {
+	attempts  int
+	errors    []string
+	notBefore time.Time // when the URL may next be tried
+}
+
+// DeadLetter is a URL that was given up on, with every error it produced.
+type DeadLetter struct {
+	URL    string
+	Errors []string
+}
+
+// region retry tracking
+
+// recordFailure notes a failed attempt at a URL and either requeues it or
+// moves it to the dead letters. A URL the server asked to wait for is held back
+// for at least that long. It returns whether the URL will be retried.
+func (m *RuntimeModel) recordFailure(crawlUrl string, errMsg string, retryable bool, wait time.Duration) bool {
+	f, ok := m.failures[crawlUrl]
+	if !ok {
+		f = &failure{}
+		m.failures[crawlUrl] = f
+	}
+
+	f.attempts++
+	f.errors = append(f.errors, fmt.Sprintf("%s attempt %d: %s", m.now().Format(time.DateTime), f.attempts, errMsg))
+
+	if !retryable || f.attempts >= m.retry.MaxAttempts {
+		m.deadLetters = append(m.deadLetters, DeadLetter{URL: crawlUrl, Errors: f.errors})
+		delete(m.failures, crawlUrl)
+
+		return false
+	}
+
+	f.notBefore = m.now().Add(max(m.retry.wait(f.attempts), wait))
+	m.queue.PushBack(crawlUrl)
+
+	return true
+}
+
+// recordSuccess forgets a URL's failures once it's been crawled.
+func (m *RuntimeModel) recordSuccess(crawlUrl string) {
+	delete(m.failures, crawlUrl)
+}
+
+// popReady takes the first URL off the queue that isn't being held back after
+// a failure. URLs that are held back keep their place.
+func (m *RuntimeModel) popReady() (string, bool) {
+	for i := range m.queue.Len() {
+		crawlUrl := m.queue.At(i)
+
+		if f, ok := m.failures[crawlUrl]; ok && m.now().Before(f.notBefore) {
+			continue
+		}
+
+		m.queue.Remove(i)
+
+		return crawlUrl, true
+	}
+
+	return "", false
+}
+
+// now is the time by the client's scheduler, so that URLs are held back on the
+// same clock that requests are spaced out by.
+func (m *RuntimeModel) now() time.Time {
+	if m.client == nil {
+		return time.Now()
+	}
+
+	return m.client.Scheduler().Now()
+}
+
+func (m *RuntimeModel) GetDeadLetters() []DeadLetter {
+	return m.deadLetters
+}
+
+// WriteDeadLetters writes each dead letter as its URL, preceded by its error
+// history in comment lines, so the output can be used as a seed file.
+func WriteDeadLetters(w io.Writer, deadLetters []DeadLetter) error {
+	for _, deadLetter := range deadLetters {
+		for _, errMsg := range deadLetter.Errors {
+			if _, err := fmt.Fprintln(w, "#", errMsg); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintln(w, deadLetter.URL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
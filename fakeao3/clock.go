@@ -0,0 +1,34 @@
+package fakeao3
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is a clock that only moves when slept on. Given to a client's
+// scheduler, it lets crawls of the fake site run without waiting out delays.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock() *Clock {
+	return &Clock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *Clock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	return ctx.Err()
+}
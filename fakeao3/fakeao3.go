@@ -0,0 +1,385 @@
+// Package fakeao3 is a stand-in for an otwarchive site, serving just enough of
+// its pages, logins, and failures for crawls to be tested without the network.
+package fakeao3
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// region consts
+
+const PageSize = 20 // works per listing page, as on AO3
+
+const sessionCookie = "_otwarchive_session"
+const credentialsCookie = "user_credentials"
+
+// region data
+
+// Work is a work on the fake site. Restricted works are hidden from listings,
+// and their pages redirect to the login form, unless logged in.
+type Work struct {
+	ID         int
+	Title      string
+	Author     string
+	Tags       []string
+	Restricted bool
+}
+
+// Series is a series on the fake site. Its works are listed in order.
+type Series struct {
+	ID     int
+	Title  string
+	Author string
+	Works  []int
+}
+
+// Fault is a response served in place of a page, such as a rate limit.
+type Fault struct {
+	Status int
+	Header http.Header
+	Body   string
+}
+
+// RetryAfter is AO3's rate limit, asking for a pause of the given seconds.
+func RetryAfter(seconds int) Fault {
+	return Fault{Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {strconv.Itoa(seconds)}}}
+}
+
+// ServerError is a 5xx error, like AO3 gives when overloaded.
+func ServerError(status int) Fault {
+	return Fault{Status: status}
+}
+
+// Challenge is Cloudflare asking the client to prove it's a browser.
+func Challenge() Fault {
+	return Fault{Status: http.StatusForbidden, Header: http.Header{"Cf-Mitigated": {"challenge"}}}
+}
+
+// RetryLaterPage is AO3's rate limit as a successful page, as it's sometimes
+// served.
+func RetryLaterPage() Fault {
+	return Fault{Status: http.StatusOK, Body: "Retry later\n"}
+}
+
+// MaintenancePage is the page shown while the site is down for maintenance,
+// served as a success.
+func MaintenancePage() Fault {
+	return Fault{Status: http.StatusOK, Body: "<!DOCTYPE html><html><head><title>Archive of Our Own</title></head><body><h1>The Archive is down for maintenance.</h1></body></html>"}
+}
+
+// region server
+
+// Server is a running fake site. Its URL is the site's base URL.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	works    []Work
+	series   []Series
+	users    map[string]string  // passwords by username
+	sessions map[string]string  // authenticity tokens by session
+	logins   map[string]string  // usernames by credentials cookie
+	faults   map[string][]Fault // by path, served in order before the page
+	requests []string           // paths and queries, in the order requested
+}
+
+// New starts a fake site with no works or users. Close it when done.
+func New() *Server {
+	s := &Server{
+		users:    map[string]string{},
+		sessions: map[string]string{},
+		logins:   map[string]string{},
+		faults:   map[string][]Fault{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tags/{tag}/works", s.tagWorks)
+	mux.HandleFunc("GET /users/{user}/works", s.userWorks)
+	mux.HandleFunc("GET /users/{user}/pseuds/{pseud}/works", s.userWorks)
+	mux.HandleFunc("GET /works/{id}", s.work)
+	mux.HandleFunc("GET /series/{id}", s.seriesPage)
+	mux.HandleFunc("GET /users/login", s.loginForm)
+	mux.HandleFunc("POST /users/login", s.login)
+	mux.HandleFunc("GET /users/{user}", s.dashboard)
+
+	s.Server = httptest.NewServer(s.withFaults(mux))
+
+	return s
+}
+
+// AddWork adds works to the site. Listings show works in the order added.
+func (s *Server) AddWork(works ...Work) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.works = append(s.works, works...)
+}
+
+func (s *Server) AddSeries(series ...Series) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.series = append(s.series, series...)
+}
+
+func (s *Server) AddUser(name, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[name] = password
+}
+
+// Fail serves the given faults, in order, to the next requests for path,
+// before going back to serving the page.
+func (s *Server) Fail(path string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults[path] = append(s.faults[path], faults...)
+}
+
+// Requests returns the path and query of every request made so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) withFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.RequestURI())
+
+		faults := s.faults[r.URL.Path]
+		if len(faults) > 0 {
+			s.faults[r.URL.Path] = faults[1:]
+		}
+		s.mu.Unlock()
+
+		if len(faults) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for name, values := range faults[0].Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(faults[0].Status)
+		io.WriteString(w, faults[0].Body)
+	})
+}
+
+// region pages
+
+func (s *Server) tagWorks(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+
+	s.listing(w, r, tag, func(work Work) bool { return slices.Contains(work.Tags, tag) })
+}
+
+func (s *Server) userWorks(w http.ResponseWriter, r *http.Request) {
+	user := r.PathValue("user")
+
+	s.listing(w, r, user, func(work Work) bool { return work.Author == user })
+}
+
+func (s *Server) listing(w http.ResponseWriter, r *http.Request, name string, include func(Work) bool) {
+	loggedIn := s.user(r) != ""
+
+	s.mu.Lock()
+	var works []Work
+	for _, work := range s.works {
+		if include(work) && (loggedIn || !work.Restricted) {
+			works = append(works, work)
+		}
+	}
+	s.mu.Unlock()
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	lastPage := max((len(works)+PageSize-1)/PageSize, 1)
+	page = min(max(page, 1), lastPage)
+
+	start, end := (page-1)*PageSize, min(page*PageSize, len(works))
+
+	render(w, listingTemplate, map[string]any{
+		"Name":     name,
+		"Path":     r.URL.Path,
+		"Start":    min(start+1, end),
+		"End":      end,
+		"Total":    len(works),
+		"Works":    s.blurbs(works[start:end]),
+		"Page":     page,
+		"LastPage": lastPage,
+		"Pages":    pageNumbers(lastPage),
+	})
+}
+
+func (s *Server) work(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+
+	s.mu.Lock()
+	i := slices.IndexFunc(s.works, func(work Work) bool { return work.ID == id })
+	if i < 0 {
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+
+	found := s.works[i]
+	s.mu.Unlock()
+
+	work := s.blurbs([]Work{found})[0]
+	if work.Restricted && s.user(r) == "" {
+		http.Redirect(w, r, "/users/login?restricted=true&return_to="+r.URL.Path, http.StatusFound)
+		return
+	}
+
+	render(w, workTemplate, work)
+}
+
+func (s *Server) seriesPage(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+
+	s.mu.Lock()
+	i := slices.IndexFunc(s.series, func(series Series) bool { return series.ID == id })
+	if i < 0 {
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+
+	series := s.series[i]
+	var works []Work
+	for _, workID := range series.Works {
+		if j := slices.IndexFunc(s.works, func(work Work) bool { return work.ID == workID }); j >= 0 {
+			works = append(works, s.works[j])
+		}
+	}
+	s.mu.Unlock()
+
+	render(w, seriesTemplate, map[string]any{"Series": series, "Works": s.blurbs(works)})
+}
+
+func (s *Server) loginForm(w http.ResponseWriter, r *http.Request) {
+	session := randomToken()
+	token := randomToken()
+
+	s.mu.Lock()
+	s.sessions[session] = token
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: session, Path: "/"})
+	render(w, loginTemplate, token)
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	session, err := r.Cookie(sessionCookie)
+	name, password := r.PostFormValue("user[login]"), r.PostFormValue("user[password]")
+
+	s.mu.Lock()
+	validToken := err == nil && s.sessions[session.Value] != "" && s.sessions[session.Value] == r.PostFormValue("authenticity_token")
+	validUser := s.users[name] != "" && s.users[name] == password
+	s.mu.Unlock()
+
+	if !validToken {
+		http.Error(w, "ActionController::InvalidAuthenticityToken", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !validUser {
+		http.Redirect(w, r, "/users/login", http.StatusFound)
+		return
+	}
+
+	credentials := randomToken()
+
+	s.mu.Lock()
+	s.logins[credentials] = name
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: credentialsCookie, Value: credentials, Path: "/"})
+	http.Redirect(w, r, "/users/"+name, http.StatusFound)
+}
+
+func (s *Server) dashboard(w http.ResponseWriter, r *http.Request) {
+	render(w, dashboardTemplate, r.PathValue("user"))
+}
+
+// region helpers
+
+// user returns who the request is logged in as, if anyone.
+func (s *Server) user(r *http.Request) string {
+	credentials, err := r.Cookie(credentialsCookie)
+	if err != nil {
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.logins[credentials.Value]
+}
+
+type seriesPosition struct {
+	Series
+	Position int
+}
+
+type blurb struct {
+	Work
+	Series []seriesPosition
+}
+
+// blurbs adds the series each work is in, for showing on a page.
+func (s *Server) blurbs(works []Work) []blurb {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blurbs := make([]blurb, 0, len(works))
+	for _, work := range works {
+		b := blurb{Work: work}
+
+		for _, series := range s.series {
+			if i := slices.Index(series.Works, work.ID); i >= 0 {
+				b.Series = append(b.Series, seriesPosition{Series: series, Position: i + 1})
+			}
+		}
+
+		blurbs = append(blurbs, b)
+	}
+
+	return blurbs
+}
+
+func pageNumbers(lastPage int) []int {
+	pages := make([]int, lastPage)
+	for i := range pages {
+		pages[i] = i + 1
+	}
+
+	return pages
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func render(w http.ResponseWriter, t *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
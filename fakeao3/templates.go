@@ -0,0 +1,104 @@
+package fakeao3
+
+import "html/template"
+
+// The templates below follow the markup of the real site's pages, trimmed to
+// what AO3Fetch reads.
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+}).Parse(`
+{{define "blurb"}}
+<li class="work blurb group" id="work_{{.ID}}" role="article">
+  <div class="header module">
+    <h4 class="heading">
+      <a href="/works/{{.ID}}">{{.Title}}</a>
+      by
+      <a rel="author" href="/users/{{.Author}}/pseuds/{{.Author}}">{{.Author}}</a>
+      {{if .Restricted}}<img alt="(Restricted)" title="Restricted" src="/images/lockblue.png">{{end}}
+    </h4>
+  </div>
+  {{if .Series}}
+  <ul class="series">
+    {{range .Series}}<li>Part <strong>{{.Position}}</strong> of <a href="/series/{{.ID}}">{{.Title}}</a></li>{{end}}
+  </ul>
+  {{end}}
+</li>
+{{end}}
+
+{{define "page"}}<!DOCTYPE html>
+<html><head><title>Archive of Our Own</title></head>
+<body><div id="outer"><div id="inner" class="wrapper"><div id="main">{{template "content" .}}</div></div></div></body></html>
+{{end}}
+`))
+
+var listingTemplate = page(`
+<h2 class="heading">{{.Start}} - {{.End}} of {{.Total}} Works in {{.Name}}</h2>
+<ol class="work index group">
+  {{range .Works}}{{template "blurb" .}}{{end}}
+</ol>
+{{if gt .LastPage 1}}
+<ol class="pagination actions" role="navigation" title="pagination">
+  <li class="previous">{{if gt .Page 1}}<a rel="prev" href="{{.Path}}?page={{add .Page -1}}">← Previous</a>{{else}}<span class="disabled">← Previous</span>{{end}}</li>
+  {{range .Pages}}<li>{{if eq . $.Page}}<span class="current">{{.}}</span>{{else}}<a href="{{$.Path}}?page={{.}}">{{.}}</a>{{end}}</li>{{end}}
+  <li class="next">{{if lt .Page .LastPage}}<a rel="next" href="{{.Path}}?page={{add .Page 1}}">Next →</a>{{else}}<span class="disabled">Next →</span>{{end}}</li>
+</ol>
+{{end}}
+`)
+
+var workTemplate = page(`
+<div class="wrapper">
+  <dl class="work meta group">
+    <dt class="rating tags">Rating:</dt>
+    <dd class="rating tags"><ul class="commas"><li><a class="tag" href="/tags/Not%20Rated/works">Not Rated</a></li></ul></dd>
+    <dt class="freeform tags">Additional Tags:</dt>
+    <dd class="freeform tags"><ul class="commas">{{range .Tags}}<li><a class="tag" href="/tags/{{.}}/works">{{.}}</a></li>{{end}}</ul></dd>
+    {{if .Series}}
+    <dt class="series">Series:</dt>
+    <dd class="series">
+      {{range .Series}}<span class="series"><span class="position">Part {{.Position}} of <a href="/series/{{.ID}}">{{.Title}}</a></span></span>{{end}}
+    </dd>
+    {{end}}
+  </dl>
+</div>
+<div id="workskin">
+  <div class="preface group">
+    <h2 class="title heading">{{.Title}}{{if .Restricted}} <img alt="(Restricted)" title="Restricted" src="/images/lockblue.png">{{end}}</h2>
+    <h3 class="byline heading"><a rel="author" href="/users/{{.Author}}/pseuds/{{.Author}}">{{.Author}}</a></h3>
+  </div>
+</div>
+`)
+
+var seriesTemplate = page(`
+<h2 class="heading">{{.Series.Title}}</h2>
+<div class="wrapper">
+  <dl class="series meta group">
+    <dt>Creator:</dt>
+    <dd><a rel="author" href="/users/{{.Series.Author}}/pseuds/{{.Series.Author}}">{{.Series.Author}}</a></dd>
+  </dl>
+</div>
+<ul class="series work index group">
+  {{range .Works}}{{template "blurb" .}}{{end}}
+</ul>
+`)
+
+var loginTemplate = page(`
+<h2 class="heading">Log In</h2>
+<form id="loginform" action="/users/login" accept-charset="UTF-8" method="post">
+  <input type="hidden" name="authenticity_token" value="{{.}}" autocomplete="off">
+  <input type="text" name="user[login]" id="user_login">
+  <input type="password" name="user[password]" id="user_password">
+  <input name="user[remember_me]" type="hidden" value="0">
+  <input type="checkbox" value="1" name="user[remember_me]" id="user_remember_me">
+  <input type="submit" name="commit" value="Log In">
+</form>
+`)
+
+var dashboardTemplate = page(`
+<h2 class="heading">{{.}}</h2>
+`)
+
+// page makes a template for a whole page from its main content.
+func page(content string) *template.Template {
+	return template.Must(template.Must(templates.Clone()).New("content").Parse(content)).Lookup("page")
+}
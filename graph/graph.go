@@ -0,0 +1,302 @@
+package graph
+
+import (
+	"cmp"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/legowerewolf/AO3fetch/ao3client"
+)
+
+// NodeKind is the type of thing a node stands for.
+type NodeKind string
+
+const (
+	KindWork     NodeKind = "work"
+	KindExternal NodeKind = "external" // an external work, bookmarked or remixed
+	KindSeries   NodeKind = "series"
+	KindCreator  NodeKind = "creator" // a user or pseud
+)
+
+// EdgeKind is the relationship an edge stands for. Edges always point from a
+// work to the thing it's related to.
+type EdgeKind string
+
+const (
+	EdgeSeries        EdgeKind = "series"        // work is part of series
+	EdgeInspiredBy    EdgeKind = "inspiredBy"    // work is inspired by (a remix, podfic... of) another
+	EdgeTranslationOf EdgeKind = "translationOf" // work is a translation of another
+	EdgeAuthor        EdgeKind = "author"        // work was written by creator
+	EdgeGift          EdgeKind = "gift"          // work was given to creator
+)
+
+type Node struct {
+	ID    string   `json:"id"` // the node's URL
+	Kind  NodeKind `json:"kind"`
+	Label string   `json:"label,omitempty"`
+}
+
+type Edge struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Kind     EdgeKind `json:"kind"`
+	Position int      `json:"position,omitempty"` // a work's place in a series
+}
+
+// Graph is the set of relationships between works, series, and creators seen
+// during a crawl.
+type Graph struct {
+	nodes map[string]*Node
+	edges map[Edge]bool
+}
+
+func New() *Graph {
+	return &Graph{
+		nodes: make(map[string]*Node),
+		edges: make(map[Edge]bool),
+	}
+}
+
+// region building
+
+// AddNode adds a node, or fills in the label of an existing one.
+func (g *Graph) AddNode(id string, kind NodeKind, label string) {
+	if node, ok := g.nodes[id]; ok {
+		if node.Label == "" {
+			node.Label = label
+		}
+
+		return
+	}
+
+	g.nodes[id] = &Node{ID: id, Kind: kind, Label: label}
+}
+
+// AddEdge adds an edge between two nodes, adding any node that isn't known yet
+// without a label.
+func (g *Graph) AddEdge(e Edge) {
+	g.AddNode(e.From, kindOf(e.From), "")
+	g.AddNode(e.To, kindOf(e.To), "")
+
+	g.edges[e] = true
+}
+
+// AddWork records a work and the series, authors, and recipients shown with
+// it.
+func (g *Graph) AddWork(w ao3client.WorkBlurb) {
+	g.AddNode(w.URL, KindWork, w.Title)
+
+	for _, series := range w.Series {
+		g.AddNode(series.URL, KindSeries, series.Title)
+		g.AddEdge(Edge{From: w.URL, To: series.URL, Kind: EdgeSeries, Position: series.Position})
+	}
+
+	for _, author := range w.Authors {
+		g.AddNode(author.URL, KindCreator, author.Name)
+		g.AddEdge(Edge{From: w.URL, To: author.URL, Kind: EdgeAuthor})
+	}
+
+	for _, recipient := range w.Recipients {
+		user := strings.TrimSuffix(recipient.URL, "/gifts")
+
+		g.AddNode(user, KindCreator, recipient.Name)
+		g.AddEdge(Edge{From: w.URL, To: user, Kind: EdgeGift})
+	}
+}
+
+// AddWorkPage records everything AddWork does, plus the works a work was
+// inspired by or translated from, and the works it inspired or was translated
+// into, which only its own page shows.
+func (g *Graph) AddWorkPage(w ao3client.Work) {
+	g.AddWork(w.WorkBlurb)
+
+	for _, parent := range w.InspiredBy {
+		g.AddEdge(Edge{From: w.URL, To: parent, Kind: EdgeInspiredBy})
+	}
+
+	for _, child := range w.Inspired {
+		g.AddEdge(Edge{From: child, To: w.URL, Kind: EdgeInspiredBy})
+	}
+
+	for _, original := range w.TranslationOf {
+		g.AddEdge(Edge{From: w.URL, To: original, Kind: EdgeTranslationOf})
+	}
+
+	for _, translation := range w.Translations {
+		g.AddEdge(Edge{From: translation, To: w.URL, Kind: EdgeTranslationOf})
+	}
+}
+
+// Nodes returns every node, sorted by ID.
+func (g *Graph) Nodes() []Node {
+	nodes := make([]Node, 0, len(g.nodes))
+	for _, node := range g.nodes {
+		nodes = append(nodes, *node)
+	}
+
+	slices.SortFunc(nodes, func(a, b Node) int { return strings.Compare(a.ID, b.ID) })
+
+	return nodes
+}
+
+// Edges returns every edge, sorted by source, then target, then kind.
+func (g *Graph) Edges() []Edge {
+	return slices.SortedFunc(maps.Keys(g.edges), func(a, b Edge) int {
+		return cmp.Or(strings.Compare(a.From, b.From), strings.Compare(a.To, b.To), strings.Compare(string(a.Kind), string(b.Kind)))
+	})
+}
+
+func kindOf(id string) NodeKind {
+	switch {
+	case strings.Contains(id, "/external_works/"):
+		return KindExternal
+	case strings.Contains(id, "/series/"):
+		return KindSeries
+	case strings.Contains(id, "/users/"):
+		return KindCreator
+	}
+
+	return KindWork
+}
+
+// region export
+
+// Formats lists the formats graphs can be written in.
+var Formats = []string{"graphml", "dot", "json"}
+
+// Write writes the graph in one of Formats.
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "graphml":
+		return g.WriteGraphML(w)
+	case "dot":
+		return g.WriteDOT(w)
+	case "json":
+		return g.WriteJSON(w)
+	}
+
+	return fmt.Errorf("unknown graph format %q", format)
+}
+
+// WriteJSON writes the graph as a single JSON object with "nodes" and "edges"
+// arrays.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(struct {
+		Nodes []Node `json:"nodes"`
+		Edges []Edge `json:"edges"`
+	}{g.Nodes(), g.Edges()})
+}
+
+// WriteDOT writes the graph in Graphviz's DOT language.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	b := strings.Builder{}
+
+	b.WriteString("digraph ao3 {\n")
+
+	for _, node := range g.Nodes() {
+		label := cmp.Or(node.Label, node.ID)
+		fmt.Fprintf(&b, "  %s [label=%s, kind=%s, URL=%s];\n", strconv.Quote(node.ID), strconv.Quote(label), strconv.Quote(string(node.Kind)), strconv.Quote(node.ID))
+	}
+
+	for _, edge := range g.Edges() {
+		attrs := "kind=" + strconv.Quote(string(edge.Kind))
+		if edge.Position > 0 {
+			attrs += fmt.Sprintf(", position=%d, label=\"%d\"", edge.Position, edge.Position)
+		}
+
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), attrs)
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// WriteGraphML writes the graph as GraphML, with node labels and kinds, edge
+// kinds, and series positions as data keys.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{Xmlns: "http://graphml.graphdrawing.org/xmlns"}
+	doc.Keys = []graphMLKey{
+		{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+		{ID: "kind", For: "node", AttrName: "kind", AttrType: "string"},
+		{ID: "relation", For: "edge", AttrName: "kind", AttrType: "string"},
+		{ID: "position", For: "edge", AttrName: "position", AttrType: "int"},
+	}
+	doc.Graph.EdgeDefault = "directed"
+
+	for _, node := range g.Nodes() {
+		data := []graphMLData{{Key: "kind", Value: string(node.Kind)}}
+		if node.Label != "" {
+			data = append(data, graphMLData{Key: "label", Value: node.Label})
+		}
+
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: node.ID, Data: data})
+	}
+
+	for _, edge := range g.Edges() {
+		data := []graphMLData{{Key: "relation", Value: string(edge.Kind)}}
+		if edge.Position > 0 {
+			data = append(data, graphMLData{Key: "position", Value: strconv.Itoa(edge.Position)})
+		}
+
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: edge.From, Target: edge.To, Data: data})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
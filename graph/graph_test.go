@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/legowerewolf/AO3fetch/ao3client"
+)
+
+const base = "https://archiveofourown.org"
+
+func testGraph() *Graph {
+	g := New()
+
+	g.AddWorkPage(ao3client.Work{
+		WorkBlurb: ao3client.WorkBlurb{
+			URL:        base + "/works/2",
+			Title:      "Podfic of \"Source\"",
+			Authors:    []ao3client.Creator{{Name: "reader", URL: base + "/users/reader/pseuds/reader"}},
+			Recipients: []ao3client.Creator{{Name: "writer", URL: base + "/users/writer/gifts"}},
+			Series:     []ao3client.SeriesPosition{{Position: 3, Title: "Podfics", URL: base + "/series/7"}},
+		},
+		InspiredBy: []string{base + "/works/1"},
+	})
+
+	return g
+}
+
+func TestAddWorkPage(t *testing.T) {
+	g := testGraph()
+
+	want := map[Edge]bool{
+		{From: base + "/works/2", To: base + "/works/1", Kind: EdgeInspiredBy}:                true,
+		{From: base + "/works/2", To: base + "/series/7", Kind: EdgeSeries, Position: 3}:      true,
+		{From: base + "/works/2", To: base + "/users/reader/pseuds/reader", Kind: EdgeAuthor}: true,
+		{From: base + "/works/2", To: base + "/users/writer", Kind: EdgeGift}:                 true,
+	}
+
+	edges := g.Edges()
+	if len(edges) != len(want) {
+		t.Fatalf("expected %d edges, got %v", len(want), edges)
+	}
+
+	for _, edge := range edges {
+		if !want[edge] {
+			t.Errorf("unexpected edge %v", edge)
+		}
+	}
+
+	for _, node := range g.Nodes() {
+		if node.ID == base+"/works/1" && (node.Kind != KindWork || node.Label != "") {
+			t.Errorf("parent work should be an unlabelled work node, got %v", node)
+		}
+	}
+}
+
+func TestAddWorkPageTranslations(t *testing.T) {
+	g := New()
+
+	g.AddWorkPage(ao3client.Work{
+		WorkBlurb:     ao3client.WorkBlurb{URL: base + "/works/2", Title: "Une Étude"},
+		TranslationOf: []string{base + "/works/1"},
+		Translations:  []string{base + "/works/3"},
+	})
+
+	// both point from the translation to what it was translated from
+	want := []Edge{
+		{From: base + "/works/2", To: base + "/works/1", Kind: EdgeTranslationOf},
+		{From: base + "/works/3", To: base + "/works/2", Kind: EdgeTranslationOf},
+	}
+
+	if edges := g.Edges(); !slices.Equal(edges, want) {
+		t.Errorf("expected %v, got %v", want, edges)
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	g := testGraph()
+
+	for _, format := range Formats {
+		buf := bytes.Buffer{}
+		if err := g.Write(&buf, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		var err error
+		switch format {
+		case "graphml":
+			err = xml.Unmarshal(buf.Bytes(), &graphML{})
+		case "json":
+			err = json.Unmarshal(buf.Bytes(), &struct{}{})
+		case "dot":
+			if !strings.Contains(buf.String(), `"`+base+`/works/2" -> "`+base+`/series/7" [kind="series", position=3, label="3"];`) {
+				t.Errorf("dot output missing series edge:\n%s", buf.String())
+			}
+		}
+
+		if err != nil {
+			t.Errorf("%s: output doesn't parse: %v", format, err)
+		}
+	}
+
+	if err := g.Write(&bytes.Buffer{}, "svg"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
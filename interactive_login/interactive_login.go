@@ -1,6 +1,7 @@
 package interactivelogin
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -19,6 +20,7 @@ import (
 func Login(client *ao3client.Ao3Client) bool {
 
 	m := newModel(client)
+	defer m.cancel()
 
 	p := tea.NewProgram(m)
 
@@ -72,6 +74,8 @@ var realizedKeymap = keymap{
 
 type model struct {
 	client *ao3client.Ao3Client
+	ctx    context.Context    // cancelled when the login screen is quit
+	cancel context.CancelFunc // aborts a login in progress
 
 	inputs  []textinput.Model
 	focused int
@@ -124,6 +128,8 @@ func newModel(client *ao3client.Ao3Client) model {
 		help: help.New(),
 	}
 
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
 	m.help.Styles.ShortKey = lipgloss.NewStyle().Faint(true).Bold(true)
 
 	m.inputs[m.focused].Focus()
@@ -143,6 +149,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, realizedKeymap.exit):
+			m.cancel()
 			return m, tea.Quit
 		}
 
@@ -192,6 +199,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case loginSuccessMsg:
 		m.success = true
 		m.status = "Login successful!"
+		m.cancel()
 		return m, tea.Quit
 
 	case loginFailedMsg:
@@ -239,7 +247,7 @@ func (m *model) attemptLogin() tea.Cmd {
 	return func() tea.Msg {
 
 		err := m.client.Authenticate(
-			m.inputs[0].Value(), m.inputs[1].Value(),
+			m.ctx, m.inputs[0].Value(), m.inputs[1].Value(),
 		)
 
 		if err == nil {
@@ -1,37 +1,117 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/url"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/legowerewolf/AO3fetch/ao3client"
+	"github.com/legowerewolf/AO3fetch/archive"
 	"github.com/legowerewolf/AO3fetch/buildinfo"
 	"github.com/legowerewolf/AO3fetch/crawler"
+	"github.com/legowerewolf/AO3fetch/graph"
 	interactivelogin "github.com/legowerewolf/AO3fetch/interactive_login"
 	"github.com/legowerewolf/AO3fetch/osc"
+	"github.com/legowerewolf/AO3fetch/scheduler"
+	"github.com/legowerewolf/AO3fetch/search"
+	"github.com/legowerewolf/AO3fetch/seeds"
 )
 
+// proxyEnvVar names the environment variable read when -proxy isn't given.
+const proxyEnvVar = "AO3FETCH_PROXY"
+
 func main() {
 	// parse flags
 	var (
-		seedURLRaw, credentials, outputFile string
-		pages, delay                        int
-		includeSeries, showVersionAndQuit   bool
+		credentials, outputFile           string
+		graphFile, graphFormat            string
+		seedFile, failuresFile            string
+		budgetAction, remainingFile       string
+		hourlyBudget, dailyBudget         int
+		outputFormat, searchSpecFile      string
+		seedURLsRaw, windowsRaw           stringList
+		proxy                             string
+		hostProxiesRaw, cacheMaxAgesRaw   stringList
+		cacheDir                          string
+		recordFile, replayFile            string
+		profileFile                       string
+		archiveName, archivesFile         string
+		canonicalHost                     string
+		failoverMinutes                   int
+		cacheOnly                         bool
+		partitionBy                       string
+		pages, delay                      int
+		maxAttempts, retryBackoff         int
+		connectTimeout, headerTimeout     int
+		requestTimeout                    int
+		partitionCount, partitionMaxPages int
+		seriesDepth, relatedDepth         int
+		authorDepth, maxAuthors           int
+		collectionDepth                   int
+		includeSeries, showVersionAndQuit bool
+		followAuthors, followRelated      bool
+		followCollections, shareSchedule  bool
 	)
 	flag.BoolVar(&showVersionAndQuit, "version", false, "Show version information and quit.")
-	flag.StringVar(&seedURLRaw, "url", "", "URL to start crawling from.")
+	flag.Var(&seedURLsRaw, "url", "URL to start crawling from: a listing, series, work, or chapter. Can be given more than once.")
+	flag.StringVar(&seedFile, "seedFile", "", "File of URLs to start crawling from, one per line. Blank lines and lines starting with # are ignored.")
+	flag.StringVar(&searchSpecFile, "search", "", "JSON file describing a filtered works search to start crawling from.")
+	flag.StringVar(&archiveName, "archive", "", "Name of the archive to crawl, for otwarchive sites other than AO3. Defaults to the archive serving the first URL.")
+	flag.StringVar(&archivesFile, "archives", "", "JSON file describing more archives, by name, for -archive.")
+	flag.StringVar(&canonicalHost, "canonicalHost", "", "Host to write all output URLs with, such as archiveofourown.org, whichever of the archive's hosts is crawled. Defaults to the crawled host.")
+	flag.IntVar(&failoverMinutes, "failover", 0, "Minutes the crawled host can fail with connection or server errors before requests switch to the archive's next mirror, or 0 to never switch.")
 	flag.IntVar(&pages, "pages", 1, "Number of pages to crawl.")
 	flag.BoolVar(&includeSeries, "series", true, "Discover and crawl series.")
-	flag.IntVar(&delay, "delay", 10, "Delay between requests in seconds.")
+	flag.IntVar(&seriesDepth, "seriesDepth", 1, "How many series deep to follow series found in other series.")
+	flag.BoolVar(&followAuthors, "authors", false, "Discover authors and crawl their works.")
+	flag.IntVar(&authorDepth, "authorDepth", 1, "How many authors deep to follow authors found in other authors' works, when using -authors.")
+	flag.IntVar(&maxAuthors, "maxAuthors", 50, "Maximum number of authors to follow when using -authors, or 0 for no limit.")
+	flag.BoolVar(&followRelated, "related", false, "Discover works inspired by, inspiring, or translated from or into the works found. Visits every work's page.")
+	flag.IntVar(&relatedDepth, "relatedDepth", 1, "How many works deep to follow related works, when using -related.")
+	flag.BoolVar(&followCollections, "collections", false, "Discover collections the works found are in and crawl their works. Visits every work's page.")
+	flag.IntVar(&collectionDepth, "collectionDepth", 1, "How many collections deep to follow collections, when using -collections.")
+	flag.StringVar(&partitionBy, "partition", "", "Split a works listing into smaller listings by revision \"date\" or work \"id\" ranges, and crawl them all.")
+	flag.IntVar(&partitionCount, "partitions", 8, "Number of partitions to start with when using -partition.")
+	flag.IntVar(&partitionMaxPages, "partitionMaxPages", 50, "Split partitions with more pages than this in half when using -partition.")
+	flag.IntVar(&delay, "delay", 10, "Delay between requests in seconds. Defaults to the archive's minimum, if that's higher.")
+	flag.IntVar(&connectTimeout, "connectTimeout", 10, "Seconds to wait for a connection to the site, or 0 for no limit.")
+	flag.IntVar(&headerTimeout, "headerTimeout", 30, "Seconds to wait for the site to start responding to a request, or 0 for no limit.")
+	flag.IntVar(&requestTimeout, "timeout", 60, "Seconds to wait for a whole request to finish, or 0 for no limit.")
+	flag.StringVar(&proxy, "proxy", "", "Proxy to send requests through, as an http://, https://, socks5://, or socks5h:// URL, optionally with user:password@ before the host; or \"direct\" for none. Defaults to $"+proxyEnvVar+", then $HTTPS_PROXY and $HTTP_PROXY.")
+	flag.Var(&hostProxiesRaw, "hostProxy", "Proxy for one host and its subdomains, in the form host=proxy, overriding -proxy. Can be given more than once.")
+	flag.BoolVar(&shareSchedule, "shareDelay", true, "Share the delay with other AO3Fetch runs on this machine, so they don't make requests to the same site any faster together.")
+	flag.StringVar(&cacheDir, "cacheDir", "", "Directory to cache fetched pages in, so they aren't fetched again while fresh. Pages aren't cached if not given.")
+	flag.Var(&cacheMaxAgesRaw, "cacheMaxAge", "How long cached pages of a kind stay fresh, in the form kind=duration (like listing=30m); kinds are "+strings.Join(ao3client.CacheRoutes, ", ")+". Can be given more than once.")
+	flag.BoolVar(&cacheOnly, "cacheOnly", false, "Use only pages in -cacheDir, however old, and make no requests to the site.")
+	flag.StringVar(&recordFile, "record", "", "Filename to record every request and response to, for replaying with -replay. Cookies and passwords are left out.")
+	flag.StringVar(&replayFile, "replay", "", "Filename of a recording made with -record to answer requests from, instead of the site.")
+	flag.StringVar(&profileFile, "profile", "", "JSON file of page selectors and login form fields to use over the built-in ones, for when AO3's pages change before AO3Fetch does.")
+	flag.IntVar(&maxAttempts, "maxAttempts", 5, "Number of times to try a page before giving up on it.")
+	flag.IntVar(&retryBackoff, "retryBackoff", 60, "Delay in seconds before a failed page is retried. Doubles with each further failure.")
+	flag.Var(&windowsRaw, "window", "Time of day to crawl during, in local time, in the form HH:MM-HH:MM. Can be given more than once. Crawls run at any time if not given.")
+	flag.IntVar(&hourlyBudget, "hourlyBudget", 0, "Maximum requests per hour to the site, shared by every run on this machine, or 0 for no limit.")
+	flag.IntVar(&dailyBudget, "dailyBudget", 0, "Maximum requests per day (UTC) to the site, shared by every run on this machine, or 0 for no limit.")
+	flag.StringVar(&budgetAction, "budgetAction", "wait", "What to do when a budget is spent: \"wait\" for it to refresh, or \"stop\".")
+	flag.StringVar(&remainingFile, "remainingFile", "", "Filename to write pages still waiting to be crawled to, if the crawl stops early. Can be used as a -seedFile later.")
+	flag.StringVar(&failuresFile, "failuresFile", "", "Filename to write pages that were given up on to, with their errors. Can be used as a -seedFile later.")
 	flag.StringVar(&credentials, "login", "", "Login credentials in the form of username:password, or \"interactive\" for interactive login.")
 	flag.StringVar(&outputFile, "outputFile", "", "Filename to write collected work URLs to instead of standard output.")
+	flag.StringVar(&outputFormat, "format", "urls", "Output format: \"urls\" for one work URL per line, or \"json\" for one JSON record per line, including bookmark data.")
+	flag.StringVar(&graphFile, "graphFile", "", "Filename to write the relationships between works, series, and creators to.")
+	flag.StringVar(&graphFormat, "graphFormat", "graphml", "Format for -graphFile: \"graphml\", \"dot\", or \"json\".")
 	flag.Parse()
 
 	if flag.NFlag() == 0 {
@@ -54,21 +134,214 @@ func main() {
 		return
 	}
 
-	var seedURL *url.URL
+	if seedFile != "" {
+		urls, err := readSeedFile(seedFile)
+		if err != nil {
+			log.Fatal("Failed to read seed file: ", err)
+		}
+
+		seedURLsRaw = append(seedURLsRaw, urls...)
+	}
+
+	archives := archive.Builtin()
+	if archivesFile != "" {
+		if err := archives.Load(archivesFile); err != nil {
+			log.Fatal("Invalid archives file:\n", err)
+		}
+	}
+
+	var site *archive.Archive
+	if archiveName != "" {
+		if site = archives[archiveName]; site == nil {
+			log.Fatal("Unknown archive; must be one of: ", strings.Join(archives.Names(), ", "))
+		}
+	}
+
+	if searchSpecFile != "" {
+		spec, err := search.LoadSpec(searchSpecFile)
+		if err != nil {
+			log.Fatal("Failed to read search spec: ", err)
+		}
+
+		if spec.Archive == "" && site != nil {
+			spec.Archive = site.BaseURL
+		}
+
+		if err := spec.Validate(); err != nil {
+			log.Fatal("Invalid search spec:\n", err)
+		}
+
+		seedURLsRaw = append(seedURLsRaw, spec.URL().String())
+	}
 
-	if seedURLRaw == "" {
+	if len(seedURLsRaw) == 0 {
 		log.Fatal("No URL provided.")
-	} else {
-		var err error
-		seedURL, err = url.Parse(seedURLRaw)
+	}
+
+	var seedList []seeds.Seed
+
+	for _, seedURLRaw := range seedURLsRaw {
+		seedURL, err := url.Parse(seedURLRaw)
 		if err != nil {
 			log.Fatal("Invalid URL provided: ", seedURLRaw)
 		}
 
+		seed, err := seeds.Classify(*seedURL)
+		if err != nil {
+			log.Fatal("Unsupported URL provided: ", err)
+		}
+
+		for _, warning := range seed.Warnings {
+			log.Println("Warning:", warning)
+		}
+
+		if seed.Kind == seeds.UserSeries && !includeSeries {
+			log.Fatal("URL lists series, so it can't be crawled with -series=false: ", seedURLRaw)
+		}
+
+		if len(seedList) > 0 && (seed.URL.Scheme != seedList[0].URL.Scheme || seed.URL.Host != seedList[0].URL.Host) {
+			log.Fatal("All URLs must be on the same site as the first: ", seedURLRaw)
+		}
+
+		seedList = append(seedList, seed)
+	}
+
+	baseURL := seedList[0].URL
+
+	if site == nil {
+		if site = archives.ForHost(baseURL.Hostname()); site == nil {
+			log.Println("Warning:", baseURL.Hostname(), "isn't a known archive's host, so it's crawled as AO3 would be; its pages may not be recognized. Use -archives and -archive to describe it.")
+			site = archive.AO3
+		}
+	} else if !site.Serves(baseURL.Hostname()) {
+		log.Fatal("URLs must be on ", site.Name, "'s hosts (", strings.Join(site.Hosts(), ", "), "), not ", baseURL.Hostname())
+	}
+
+	if canonicalHost != "" {
+		if !site.Serves(canonicalHost) {
+			log.Fatal("Canonical host must be one of ", site.Name, "'s hosts: ", strings.Join(site.Hosts(), ", "))
+		}
+
+		for i := range seedList {
+			seedList[i].URL.Host = canonicalHost
+		}
+	}
+
+	if failoverMinutes < 0 {
+		log.Fatal("Failover time must be 0 (never) or greater.")
+	}
+
+	mirrors := site.FailoverHosts(baseURL.Hostname())
+	if failoverMinutes > 0 && len(mirrors) == 0 {
+		log.Fatal(site.Name, " has no mirrors to fail over to.")
+	}
+
+	if outputFormat != "urls" && outputFormat != "json" {
+		log.Fatal("Output format must be \"urls\" or \"json\".")
+	}
+
+	if !slices.Contains(graph.Formats, graphFormat) {
+		log.Fatal("Graph format must be one of: ", strings.Join(graph.Formats, ", "))
+	}
+
+	delaySet := false
+	flag.Visit(func(f *flag.Flag) { delaySet = delaySet || f.Name == "delay" })
+
+	if delay < site.MinDelay && !delaySet {
+		delay = site.MinDelay
+	}
+
+	if delay < site.MinDelay {
+		log.Fatal("Delay must be greater than or equal to ", site.MinDelay, " for ", site.Name, ".")
+	}
+
+	var windows scheduler.Windows
+	for _, windowRaw := range windowsRaw {
+		window, err := scheduler.ParseWindow(windowRaw)
+		if err != nil {
+			log.Fatal("Invalid window: ", err)
+		}
+
+		windows = append(windows, window)
+	}
+
+	if hourlyBudget < 0 || dailyBudget < 0 {
+		log.Fatal("Budgets must be 0 (no limit) or greater.")
+	}
+
+	if budgetAction != "wait" && budgetAction != "stop" {
+		log.Fatal("Budget action must be \"wait\" or \"stop\".")
+	}
+
+	if connectTimeout < 0 || headerTimeout < 0 || requestTimeout < 0 {
+		log.Fatal("Timeouts must be 0 (no limit) or greater.")
+	}
+
+	proxyConfig := ao3client.ProxyConfig{Default: cmp.Or(proxy, os.Getenv(proxyEnvVar)), Hosts: map[string]string{}}
+	if proxyConfig.Default != "" {
+		if _, err := ao3client.ParseProxy(proxyConfig.Default); err != nil {
+			log.Fatal("Invalid proxy: ", err)
+		}
+	}
+
+	for _, hostProxy := range hostProxiesRaw {
+		host, proxy, found := strings.Cut(hostProxy, "=")
+		if !found || host == "" {
+			log.Fatal("Host proxies must be in the form host=proxy.")
+		}
+
+		if _, err := ao3client.ParseProxy(proxy); err != nil {
+			log.Fatal("Invalid proxy for ", host, ": ", err)
+		}
+
+		proxyConfig.Hosts[host] = proxy
+	}
+
+	cacheConfig := ao3client.CacheConfig{Dir: cacheDir, MaxAge: map[string]time.Duration{}, Only: cacheOnly}
+	for _, maxAgeRaw := range cacheMaxAgesRaw {
+		route, age, found := strings.Cut(maxAgeRaw, "=")
+		maxAge, err := time.ParseDuration(age)
+
+		if !found || err != nil || maxAge < 0 || !slices.Contains(ao3client.CacheRoutes, route) {
+			log.Fatal("Cache max ages must be in the form kind=duration, where kind is one of: ", strings.Join(ao3client.CacheRoutes, ", "))
+		}
+
+		cacheConfig.MaxAge[route] = maxAge
+	}
+
+	if cacheOnly && cacheDir == "" {
+		log.Fatal("-cacheOnly needs a -cacheDir to read pages from.")
+	}
+
+	if cacheOnly && credentials != "" {
+		log.Fatal("Can't log in with -cacheOnly, as logging in needs the site.")
+	}
+
+	if recordFile != "" && replayFile != "" {
+		log.Fatal("Can't -record and -replay at once.")
+	}
+
+	if replayFile != "" && credentials != "" {
+		log.Fatal("Can't log in with -replay, as logging in needs the site.")
+	}
+
+	// offline crawls make no requests, so nothing needs to hold them back
+	offline := cacheOnly || replayFile != ""
+
+	profile := site.Profile()
+	if profileFile != "" {
+		var err error
+		if profile, err = profile.Load(profileFile); err != nil {
+			log.Fatal("Invalid profile: ", err)
+		}
+	}
+
+	if maxAttempts < 1 {
+		log.Fatal("Maximum attempts must be greater than 0.")
 	}
 
-	if delay < 10 {
-		log.Fatal("Delay must be greater than or equal to 10.")
+	if retryBackoff < 0 {
+		log.Fatal("Retry backoff must not be negative.")
 	}
 
 	var outputFileHandle *os.File
@@ -81,15 +354,120 @@ func main() {
 		defer outputFileHandle.Close()
 	}
 
+	var graphFileHandle *os.File
+	if graphFile != "" {
+		var err error
+		graphFileHandle, err = os.Create(graphFile)
+		if err != nil {
+			log.Fatal("Failed to open graph file for writing: ", err)
+		}
+		defer graphFileHandle.Close()
+	}
+
+	var failuresFileHandle *os.File
+	if failuresFile != "" {
+		var err error
+		failuresFileHandle, err = os.Create(failuresFile)
+		if err != nil {
+			log.Fatal("Failed to open failures file for writing: ", err)
+		}
+		defer failuresFileHandle.Close()
+	}
+
+	var remainingFileHandle *os.File
+	if remainingFile != "" {
+		var err error
+		remainingFileHandle, err = os.Create(remainingFile)
+		if err != nil {
+			log.Fatal("Failed to open remaining file for writing: ", err)
+		}
+		defer remainingFileHandle.Close()
+	}
+
+	var recorder *ao3client.Recorder
+	if recordFile != "" {
+		recordFileHandle, err := os.Create(recordFile)
+		if err != nil {
+			log.Fatal("Failed to open recording file for writing: ", err)
+		}
+		defer recordFileHandle.Close()
+
+		recorder = ao3client.NewRecorder(recordFileHandle)
+	}
+
+	var replayer *ao3client.Replayer
+	if replayFile != "" {
+		replayFileHandle, err := os.Open(replayFile)
+		if err != nil {
+			log.Fatal("Failed to open recording to replay: ", err)
+		}
+
+		replayer, err = ao3client.NewReplayer(replayFileHandle)
+		replayFileHandle.Close()
+		if err != nil {
+			log.Fatal("Failed to read recording to replay: ", err)
+		}
+	}
+
 	// initialize client so we can check credentials if they're provided
 	var err error
-	client, err := ao3client.NewAo3Client(seedURLRaw)
+	schedConfig := scheduler.Config{Delay: time.Duration(delay) * time.Second}
+
+	if !offline {
+		schedConfig.Windows = windows
+	}
+
+	if shareSchedule && !offline {
+		coordinator, err := newCoordinator(baseURL.Host)
+		if err != nil {
+			log.Println("Warning: couldn't share the delay with other runs, so it applies to this run only:", err)
+		} else {
+			schedConfig.Coordinator = coordinator
+		}
+	}
+
+	if (hourlyBudget > 0 || dailyBudget > 0) && !offline {
+		ledger, err := newLedger(baseURL.Host)
+		if err != nil {
+			log.Fatal("Failed to open request ledger: ", err)
+		}
+
+		schedConfig.Budget = scheduler.Budget{PerHour: hourlyBudget, PerDay: dailyBudget}
+		schedConfig.Ledger = ledger
+		schedConfig.StopWhenSpent = budgetAction == "stop"
+	}
+
+	sched := scheduler.New(schedConfig)
+
+	if open, opens := sched.WindowOpen(); !open {
+		log.Println("Outside of crawl windows; requests will wait until", opens.Format("15:04"))
+	}
+
+	client, err := ao3client.NewAo3Client(baseURL.String(), ao3client.Options{
+		Scheduler:             sched,
+		ConnectTimeout:        time.Duration(connectTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(headerTimeout) * time.Second,
+		Timeout:               time.Duration(requestTimeout) * time.Second,
+		Proxy:                 proxyConfig,
+		Cache:                 cacheConfig,
+		Record:                recorder,
+		Replay:                replayer,
+		Profile:               profile,
+		UserAgent:             site.UserAgent,
+		CanonicalHost:         canonicalHost,
+		Mirrors:               mirrors,
+		FailoverAfter:         time.Duration(failoverMinutes) * time.Minute,
+	})
 	if err != nil {
 		log.Fatal("AO3 client initialization failed: ", err)
 	}
 
 	if credentials != "" {
-		if seedURL.Scheme != "https" {
+		if !site.Login {
+			log.Fatal("Logging in isn't supported on ", site.Name, ".")
+		}
+
+		if baseURL.Scheme != "https" {
 			log.Fatal("Credentials cannot be used with insecure URLs.")
 		}
 
@@ -113,7 +491,10 @@ func main() {
 
 			log.Println("Logging in as " + username + "...")
 
-			err := client.Authenticate(username, pass)
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			err := client.Authenticate(ctx, username, pass)
+			stop()
+
 			if err != nil {
 				log.Println("Login failed. Check your credentials and try again.")
 				log.Println(err)
@@ -127,21 +508,136 @@ func main() {
 		credentials = ""
 	}
 
+	if includeSeries && seriesDepth < 1 {
+		log.Fatal("Series depth must be greater than 0.")
+	}
+
+	if followAuthors && authorDepth < 1 {
+		log.Fatal("Author depth must be greater than 0.")
+	}
+
+	if followRelated && relatedDepth < 1 {
+		log.Fatal("Related work depth must be greater than 0.")
+	}
+
+	if followCollections && collectionDepth < 1 {
+		log.Fatal("Collection depth must be greater than 0.")
+	}
+
+	if graphFile != "" && !followRelated && !followCollections {
+		log.Println("Warning: work pages are only visited with -related or -collections, so the graph won't include which works inspired or translate which.")
+	}
+
+	if maxAuthors < 0 {
+		log.Fatal("Maximum authors must be 0 (no limit) or greater.")
+	}
+
 	if pages < 1 && pages != -1 {
 		log.Fatal("Number of pages must be -1 (autodetect) or greater than 0.")
 	}
 
+	if partitionBy != "" {
+		if partitionMaxPages < 1 {
+			log.Fatal("Maximum pages per partition must be greater than 0.")
+		}
+
+		if pages != -1 {
+			log.Println("Partitioned crawls always autodetect page counts.")
+			pages = -1
+		}
+
+		var partitioned []seeds.Seed
+
+		for _, seed := range seedList {
+			if !seed.Kind.IsListing() || seed.Kind == seeds.Series {
+				partitioned = append(partitioned, seed)
+				continue
+			}
+
+			partitions, err := search.Partition(seed.URL, partitionBy, partitionCount)
+			if err != nil {
+				log.Fatal("Failed to partition URL: ", err)
+			}
+
+			for _, partition := range partitions {
+				partitioned = append(partitioned, seeds.Seed{URL: partition, Kind: seed.Kind})
+			}
+		}
+
+		seedList = partitioned
+	} else {
+		partitionMaxPages = 0
+	}
+
 	// parameters all check out, finish initializing
 
 	// initialization done, start scraping
 
 	log.Println("Scrape parameters: ")
-	fmt.Println("URL:     ", seedURL)
+	fmt.Println("Archive: ", site.Name)
+	if canonicalHost != "" {
+		fmt.Println("Canonical:", canonicalHost)
+	}
+	if failoverMinutes > 0 {
+		fmt.Println("Failover:", "after", failoverMinutes, "minutes, to", strings.Join(mirrors, ", "))
+	}
+	for _, seed := range seedList {
+		fmt.Println("URL:     ", seed.URL.String(), "("+seed.Kind.String()+")")
+	}
 	fmt.Println("Pages:   ", pages)
+	if partitionBy != "" {
+		fmt.Println("Partition:", partitionBy)
+	}
 	fmt.Println("Series?: ", includeSeries)
+	fmt.Println("Authors?:", followAuthors)
+	fmt.Println("Related?:", followRelated)
+	fmt.Println("Collections?:", followCollections)
 	fmt.Println("Delay:   ", delay)
+	if len(windows) > 0 {
+		fmt.Println("Windows: ", windowsRaw.String())
+	}
+	if hourlyBudget > 0 || dailyBudget > 0 {
+		fmt.Println("Budget:  ", hourlyBudget, "per hour,", dailyBudget, "per day, then", budgetAction)
+	}
+	if proxyConfig.Default != "" || len(proxyConfig.Hosts) > 0 {
+		fmt.Println("Proxy:   ", proxyString(proxyConfig))
+	}
+	if recordFile != "" {
+		fmt.Println("Record:  ", recordFile)
+	}
+	if replayFile != "" {
+		fmt.Println("Replay:  ", replayFile)
+	}
+	if cacheOnly {
+		fmt.Println("Cache:   ", cacheDir, "(only)")
+	} else if cacheDir != "" {
+		fmt.Println("Cache:   ", cacheDir)
+	}
+	if profileFile != "" {
+		fmt.Println("Profile: ", profileFile)
+	}
+	fmt.Println("Format:  ", outputFormat)
+	if graphFile != "" {
+		fmt.Println("Graph:   ", graphFile, "("+graphFormat+")")
+	}
+
+	config := crawler.Config{
+		Pages:             pages,
+		PartitionMaxPages: partitionMaxPages,
+		Discovery: crawler.Discovery{
+			Series:      crawler.Rule{Enabled: includeSeries, Depth: seriesDepth},
+			Authors:     crawler.Rule{Enabled: followAuthors, Depth: authorDepth, Max: maxAuthors},
+			Related:     crawler.Rule{Enabled: followRelated, Depth: relatedDepth},
+			Collections: crawler.Rule{Enabled: followCollections, Depth: collectionDepth},
+		},
+		Retry: crawler.RetryPolicy{
+			MaxAttempts: maxAttempts,
+			Backoff:     time.Duration(retryBackoff) * time.Second,
+			Factor:      2,
+		},
+	}
 
-	p := tea.NewProgram(crawler.InitRuntimeModel(includeSeries, delay, *seedURL, pages, client), tea.WithAltScreen())
+	p := tea.NewProgram(crawler.InitRuntimeModel(config, seedList, client), tea.WithAltScreen())
 
 	r, err := p.Run()
 	fmt.Print(osc.SetProgress(0, 0))
@@ -157,7 +653,16 @@ func main() {
 	rModel.Logs.Dump(os.Stdout)
 
 	fmt.Println()
-	log.Printf("Found %d works across %d pages. \n", rModel.GetWorkCount(), rModel.GetPagesCrawled())
+	log.Printf("Found %d works and %d bookmarks across %d pages. \n", rModel.GetWorkCount(), rModel.GetBookmarkCount(), rModel.GetPagesCrawled())
+	if deadLetters := rModel.GetDeadLetters(); len(deadLetters) > 0 {
+		log.Printf("Gave up on %d pages.\n", len(deadLetters))
+	}
+	if shifted := rModel.GetShiftedWorkCount(); shifted > 0 {
+		log.Printf("Listings changed during the crawl; up to %d works shifted between pages. Affected pages were recrawled.\n", shifted)
+	}
+	if unrecognized := rModel.GetUnrecognizedPages(); len(unrecognized) > 0 {
+		log.Printf("WARNING: %d listing pages counted results, but none were recognized, so works were probably missed. AO3's pages may have changed; please report this. A -profile can fix the selectors in the meantime.\n", len(unrecognized))
+	}
 	fmt.Println()
 
 	var workOutputTarget io.Writer
@@ -170,8 +675,122 @@ func main() {
 		workOutputTarget = log.Writer()
 	}
 
-	for url := range rModel.GetWorks() {
-		fmt.Fprintln(workOutputTarget, url)
+	switch outputFormat {
+	case "urls":
+		for url := range rModel.GetWorks() {
+			fmt.Fprintln(workOutputTarget, url)
+		}
+	case "json":
+		encoder := json.NewEncoder(workOutputTarget)
+		encoder.SetEscapeHTML(false)
+
+		for _, record := range rModel.GetRecords() {
+			if err := encoder.Encode(record); err != nil {
+				log.Fatal("Failed to write record: ", err)
+			}
+		}
+	}
+
+	if remainingFileHandle != nil {
+		if remaining := rModel.GetRemaining(); len(remaining) > 0 {
+			log.Printf("Writing %d pages still to crawl to file %s...", len(remaining), remainingFile)
+
+			for _, url := range remaining {
+				fmt.Fprintln(remainingFileHandle, url)
+			}
+		}
+	}
+
+	if failuresFileHandle != nil {
+		log.Printf("Writing failures to file %s...", failuresFile)
+
+		if err := crawler.WriteDeadLetters(failuresFileHandle, rModel.GetDeadLetters()); err != nil {
+			log.Fatal("Failed to write failures: ", err)
+		}
+	}
+
+	if graphFileHandle != nil {
+		log.Printf("Writing graph to file %s...", graphFile)
+
+		if err := rModel.GetGraph().Write(graphFileHandle, graphFormat); err != nil {
+			log.Fatal("Failed to write graph: ", err)
+		}
+	}
+
+	if recorder != nil {
+		if err := recorder.Err(); err != nil {
+			log.Println("Warning: the recording is incomplete:", err)
+		}
+	}
+
+}
+
+func newCoordinator(host string) (*scheduler.FileCoordinator, error) {
+	dir, err := scheduler.DefaultStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduler.NewFileCoordinator(dir, host)
+}
+
+func newLedger(host string) (*scheduler.Ledger, error) {
+	dir, err := scheduler.DefaultStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduler.NewLedger(dir, host)
+}
+
+// readSeedFile reads URLs from a file with one per line, skipping blank lines
+// and # comments.
+func readSeedFile(filename string) ([]string, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for line := range strings.Lines(string(contents)) {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		urls = append(urls, line)
+	}
+
+	return urls, nil
+}
+
+// proxyString describes a proxy config without any passwords in it.
+func proxyString(config ao3client.ProxyConfig) string {
+	describe := func(proxy string) string {
+		if u, err := ao3client.ParseProxy(proxy); err == nil && u != nil {
+			return u.Redacted()
+		}
+
+		return proxy
 	}
 
+	parts := []string{describe(cmp.Or(config.Default, "from environment"))}
+	for _, host := range slices.Sorted(maps.Keys(config.Hosts)) {
+		parts = append(parts, host+"="+describe(config.Hosts[host]))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
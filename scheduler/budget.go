@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/atomicfile"
+)
+
+// region consts
+
+const hourLayout = "2006-01-02T15"
+
+var ErrBudgetSpent = errors.New("Request budget spent")
+
+// region budget
+
+// Budget caps how many requests may be made to a site per clock hour and per
+// day, in UTC. Zero means no cap.
+type Budget struct {
+	PerHour int
+	PerDay  int
+}
+
+func (b Budget) limited() bool {
+	return b.PerHour > 0 || b.PerDay > 0
+}
+
+// Ledger counts the requests made to a site by every run on this machine, in
+// a file kept next to the shared schedule.
+type Ledger struct {
+	path string
+	lock fileLock
+}
+
+type ledgerState struct {
+	Hour     string `json:"hour"`
+	HourUsed int    `json:"hourUsed"`
+	Day      string `json:"day"`
+	DayUsed  int    `json:"dayUsed"`
+}
+
+// NewLedger creates a ledger for requests to host, keeping it in dir.
+func NewLedger(dir string, host string) (*Ledger, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	name := stateFileName(host)
+
+	return &Ledger{
+		path: filepath.Join(dir, name+".ledger"),
+		lock: fileLock{path: filepath.Join(dir, name+".ledger.lock"), clock: RealClock},
+	}, nil
+}
+
+// Spend records a request at now, if the budget allows it. Otherwise it
+// returns ErrBudgetSpent and when enough of the budget will be refreshed for
+// the request to be made.
+func (l *Ledger) Spend(now time.Time, budget Budget) (refresh time.Time, err error) {
+	if err := l.lock.acquire(); err != nil {
+		return time.Time{}, err
+	}
+	defer l.lock.release()
+
+	state := l.read(now)
+
+	if refresh, spent := state.refresh(now, budget); spent {
+		return refresh, ErrBudgetSpent
+	}
+
+	state.HourUsed++
+	state.DayUsed++
+
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Time{}, atomicfile.WriteFile(l.path, contents)
+}
+
+// Remaining returns how many requests are left this hour and today, with -1
+// for no cap, and when the budget is next refreshed if it's spent.
+func (l *Ledger) Remaining(now time.Time, budget Budget) (hour, day int, refresh time.Time) {
+	state := l.read(now)
+
+	hour, day = -1, -1
+	if budget.PerHour > 0 {
+		hour = max(budget.PerHour-state.HourUsed, 0)
+	}
+	if budget.PerDay > 0 {
+		day = max(budget.PerDay-state.DayUsed, 0)
+	}
+
+	refresh, _ = state.refresh(now, budget)
+
+	return
+}
+
+// read loads the counts for the current hour and day; counts for earlier ones
+// are dropped.
+func (l *Ledger) read(now time.Time) ledgerState {
+	hourKey, dayKey := now.UTC().Format(hourLayout), now.UTC().Format(time.DateOnly)
+
+	state := ledgerState{}
+	if contents, err := os.ReadFile(l.path); err == nil {
+		json.Unmarshal(contents, &state)
+	}
+
+	if state.Hour != hourKey {
+		state.Hour, state.HourUsed = hourKey, 0
+	}
+	if state.Day != dayKey {
+		state.Day, state.DayUsed = dayKey, 0
+	}
+
+	return state
+}
+
+// refresh reports whether the budget is spent, and if so, when it's refreshed.
+func (s ledgerState) refresh(now time.Time, budget Budget) (time.Time, bool) {
+	now = now.UTC()
+
+	if budget.PerDay > 0 && s.DayUsed >= budget.PerDay {
+		return now.Truncate(24 * time.Hour).Add(24 * time.Hour), true
+	}
+
+	if budget.PerHour > 0 && s.HourUsed >= budget.PerHour {
+		return now.Truncate(time.Hour).Add(time.Hour), true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBudget(t *testing.T) {
+	ledger, err := NewLedger(t.TempDir(), "archiveofourown.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget := Budget{PerHour: 2, PerDay: 3}
+	now := time.Date(2024, time.January, 1, 22, 30, 0, 0, time.UTC)
+
+	for range 2 {
+		if _, err := ledger.Spend(now, budget); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refresh, err := ledger.Spend(now, budget)
+	if !errors.Is(err, ErrBudgetSpent) || !refresh.Equal(time.Date(2024, time.January, 1, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the hourly budget to be spent until 23:00, got %s, %v", refresh, err)
+	}
+
+	// the next hour has room, but only one request is left for the day
+	now = now.Add(time.Hour)
+
+	if hour, day, _ := ledger.Remaining(now, budget); hour != 2 || day != 1 {
+		t.Errorf("expected 2 left this hour and 1 today, got %d and %d", hour, day)
+	}
+
+	ledger.Spend(now, budget)
+
+	refresh, err = ledger.Spend(now, budget)
+	if !errors.Is(err, ErrBudgetSpent) || !refresh.Equal(time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the daily budget to be spent until midnight, got %s, %v", refresh, err)
+	}
+}
+
+func TestSchedulerWaitsForBudget(t *testing.T) {
+	ledger, err := NewLedger(t.TempDir(), "archiveofourown.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Date(2024, time.January, 1, 12, 59, 0, 0, time.UTC)}
+	budget := Budget{PerHour: 1}
+
+	waiting := New(Config{Clock: clock, Budget: budget, Ledger: ledger})
+
+	if err := waiting.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := waiting.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !clock.Now().Equal(time.Date(2024, time.January, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected to wait for the budget to refresh at 13:00, now %s", clock.Now())
+	}
+
+	stopping := New(Config{Clock: clock, Budget: budget, Ledger: ledger, StopWhenSpent: true})
+
+	if err := stopping.Wait(context.Background()); !errors.Is(err, ErrBudgetSpent) {
+		t.Errorf("expected a stopping scheduler to fail once the budget is spent, got %v", err)
+	}
+}
@@ -0,0 +1,374 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// region consts
+
+// MinimumDelay is the shortest interval between requests a Scheduler allows.
+const MinimumDelay = 10 * time.Second
+
+const DefaultBackoffFactor = 1.3
+const DefaultDecayFactor = 0.9
+
+var ErrInvalidRetryAfter = errors.New("Invalid Retry-After header")
+
+// region clock
+
+// Clock is the source of time for a Scheduler, so that tests can control it.
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for d to pass, or for ctx to be done, in which case it
+	// returns ctx's error.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RealClock is the system clock.
+var RealClock Clock = realClock{}
+
+// region scheduler
+
+// Config holds the settings for a Scheduler. Zero values are replaced with
+// defaults.
+type Config struct {
+	Delay         time.Duration // interval between requests; at least MinimumDelay
+	Burst         int           // requests that may be made back to back after a quiet spell
+	BackoffFactor float64       // growth of the interval after a failure
+	DecayFactor   float64       // shrinkage of the interval after a success, down to Delay
+	Clock         Clock
+	Coordinator   Coordinator // shares the schedule with other processes; nil for none
+	Budget        Budget
+	Ledger        *Ledger // counts requests against Budget; required for a Budget to apply
+	StopWhenSpent bool    // fail requests once the budget is spent, instead of waiting for it to refresh
+	Windows       Windows // times of day requests may be made; none for any time
+}
+
+// Scheduler spaces out requests to one site with a token bucket. The bucket
+// refills at one token per interval; the interval grows when requests fail and
+// shrinks back when they succeed, and the server can pause it entirely. A
+// Scheduler is safe for concurrent use, so every request to a site can share
+// one.
+type Scheduler struct {
+	mu          sync.Mutex
+	clock       Clock
+	coordinator Coordinator
+
+	budget        Budget
+	ledger        *Ledger
+	stopWhenSpent bool
+	windows       Windows
+
+	minDelay      time.Duration
+	delay         time.Duration
+	burst         float64
+	backoffFactor float64
+	decayFactor   float64
+
+	tokens      float64 // may go negative when requests are waiting
+	refilled    time.Time
+	pausedUntil time.Time
+}
+
+func New(config Config) *Scheduler {
+	s := &Scheduler{
+		clock:         config.Clock,
+		coordinator:   config.Coordinator,
+		budget:        config.Budget,
+		ledger:        config.Ledger,
+		stopWhenSpent: config.StopWhenSpent,
+		windows:       config.Windows,
+		minDelay:      max(config.Delay, MinimumDelay),
+		burst:         float64(max(config.Burst, 1)),
+		backoffFactor: config.BackoffFactor,
+		decayFactor:   config.DecayFactor,
+	}
+
+	if s.clock == nil {
+		s.clock = RealClock
+	}
+	if s.backoffFactor <= 1 {
+		s.backoffFactor = DefaultBackoffFactor
+	}
+	if s.decayFactor <= 0 || s.decayFactor >= 1 {
+		s.decayFactor = DefaultDecayFactor
+	}
+
+	s.delay = s.minDelay
+	s.tokens = s.burst
+	s.refilled = s.clock.Now()
+
+	return s
+}
+
+// Wait blocks until a request may be made, and claims the right to make it,
+// both in this process and, with a Coordinator, across processes. It returns
+// ErrBudgetSpent if the request budget is spent and the scheduler is set to
+// stop, ctx's error if ctx is done first, or an error if the budget couldn't be
+// checked.
+func (s *Scheduler) Wait(ctx context.Context) error {
+	s.mu.Lock()
+
+	now := s.clock.Now()
+	s.refill(now)
+
+	at := s.next(now)
+	s.tokens--
+
+	s.mu.Unlock()
+
+	if wait := at.Sub(now); wait > 0 {
+		if err := s.clock.Sleep(ctx, wait); err != nil {
+			s.refund()
+			return err
+		}
+	}
+
+	if err := s.waitWindow(ctx); err != nil {
+		return err
+	}
+
+	if err := s.waitBudget(ctx); err != nil {
+		return err
+	}
+
+	return s.waitShared(ctx)
+}
+
+// refund returns the token taken by a wait that was cancelled.
+func (s *Scheduler) refund() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens++
+}
+
+// waitWindow blocks until a window is open.
+func (s *Scheduler) waitWindow(ctx context.Context) error {
+	now := s.clock.Now()
+
+	if wait := s.windows.NextOpen(now).Sub(now); wait > 0 {
+		return s.clock.Sleep(ctx, wait)
+	}
+
+	return nil
+}
+
+// WindowOpen reports whether requests may be made at this time of day, and if
+// not, when they next may be.
+func (s *Scheduler) WindowOpen() (bool, time.Time) {
+	now := s.clock.Now()
+	next := s.windows.NextOpen(now)
+
+	return !next.After(now), next
+}
+
+// ETA returns when the given number of requests will have been made at the
+// current interval, starting from start, allowing for closed windows.
+func (s *Scheduler) ETA(start time.Time, requests int) time.Time {
+	return s.windows.Finish(start, s.Delay()*time.Duration(max(requests, 0)))
+}
+
+// waitBudget blocks until the request budget allows a request, and spends it.
+func (s *Scheduler) waitBudget(ctx context.Context) error {
+	if s.ledger == nil || !s.budget.limited() {
+		return nil
+	}
+
+	for {
+		now := s.clock.Now()
+
+		refresh, err := s.ledger.Spend(now, s.budget)
+		if !errors.Is(err, ErrBudgetSpent) || s.stopWhenSpent {
+			return err
+		}
+
+		if err := s.clock.Sleep(ctx, refresh.Sub(now)); err != nil {
+			return err
+		}
+	}
+}
+
+// Remaining returns how many requests the budget has left this hour and
+// today, with -1 for no cap, and when it's next refreshed if it's spent. It
+// returns false if there's no budget.
+func (s *Scheduler) Remaining() (hour, day int, refresh time.Time, ok bool) {
+	if s.ledger == nil || !s.budget.limited() {
+		return -1, -1, time.Time{}, false
+	}
+
+	hour, day, refresh = s.ledger.Remaining(s.clock.Now(), s.budget)
+
+	return hour, day, refresh, true
+}
+
+// StopsWhenSpent reports whether requests fail, rather than wait, once the
+// budget is spent.
+func (s *Scheduler) StopsWhenSpent() bool {
+	return s.stopWhenSpent
+}
+
+// waitShared blocks until the slot shared with other processes is claimed.
+// Coordination is best effort: if the shared state can't be used, only this
+// process's schedule applies.
+func (s *Scheduler) waitShared(ctx context.Context) error {
+	if s.coordinator == nil {
+		return nil
+	}
+
+	for {
+		wait, err := s.coordinator.Claim(s.clock.Now(), s.Delay())
+		if err != nil || wait <= 0 {
+			return nil
+		}
+
+		if err := s.clock.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Next returns when the next request could be made without waiting.
+func (s *Scheduler) Next() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.refill(now)
+
+	next := s.next(now)
+	if s.coordinator != nil {
+		next = later(next, s.coordinator.Peek())
+	}
+	if s.ledger != nil && s.budget.limited() && !s.stopWhenSpent {
+		_, _, refresh := s.ledger.Remaining(now, s.budget)
+		next = later(next, refresh)
+	}
+
+	next = s.windows.NextOpen(next)
+
+	return next
+}
+
+// Now returns the time by the scheduler's clock.
+func (s *Scheduler) Now() time.Time {
+	return s.clock.Now()
+}
+
+// Delay returns the current interval between requests.
+func (s *Scheduler) Delay() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delay
+}
+
+// Success shrinks the interval back towards its minimum.
+func (s *Scheduler) Success() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refill(s.clock.Now())
+	s.delay = max(s.minDelay, time.Duration(float64(s.delay)*s.decayFactor))
+}
+
+// Failure grows the interval.
+func (s *Scheduler) Failure() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refill(s.clock.Now())
+	s.delay = time.Duration(float64(s.delay) * s.backoffFactor)
+}
+
+// Pause holds back all requests for at least d.
+func (s *Scheduler) Pause(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pausedUntil = later(s.pausedUntil, s.clock.Now().Add(d))
+}
+
+// Observe adjusts the schedule for the outcome of a request: pausing for a
+// Retry-After header, backing off for errors, rate limiting, Cloudflare
+// challenges and server errors, and speeding back up otherwise.
+func (s *Scheduler) Observe(resp *http.Response, err error) {
+	if err != nil || resp == nil {
+		s.Failure()
+		return
+	}
+
+	if header := resp.Header.Get("Retry-After"); header != "" {
+		if wait, err := ParseRetryAfter(header, s.clock.Now()); err == nil {
+			s.Pause(wait)
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 || resp.Header.Get("cf-mitigated") == "challenge" {
+		s.Failure()
+		return
+	}
+
+	s.Success()
+}
+
+// refill adds the tokens earned since the last refill. Callers must hold mu.
+func (s *Scheduler) refill(now time.Time) {
+	if elapsed := now.Sub(s.refilled); elapsed > 0 {
+		s.tokens = min(s.burst, s.tokens+float64(elapsed)/float64(s.delay))
+	}
+
+	s.refilled = now
+}
+
+// next returns when a token will next be available. Callers must hold mu.
+func (s *Scheduler) next(now time.Time) time.Time {
+	at := now
+	if s.tokens < 1 {
+		at = now.Add(time.Duration((1 - s.tokens) * float64(s.delay)))
+	}
+
+	return later(at, s.pausedUntil)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+// ParseRetryAfter reads a Retry-After header, which holds either a number of
+// seconds or an HTTP date, as a duration from now.
+func ParseRetryAfter(header string, now time.Time) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return date.Sub(now), nil
+	}
+
+	return 0, ErrInvalidRetryAfter
+}
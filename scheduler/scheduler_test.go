@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when slept on.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func newTestScheduler(config Config) (*Scheduler, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	config.Clock = clock
+
+	return New(config), clock
+}
+
+// elapsed returns how long n calls to Wait take.
+func elapsed(s *Scheduler, clock *fakeClock, n int) time.Duration {
+	start := clock.Now()
+	for range n {
+		s.Wait(context.Background())
+	}
+
+	return clock.Now().Sub(start)
+}
+
+func TestMinimumDelay(t *testing.T) {
+	s, clock := newTestScheduler(Config{Delay: time.Second})
+
+	if got := elapsed(s, clock, 3); got != 2*MinimumDelay {
+		t.Errorf("expected three requests to take %s, took %s", 2*MinimumDelay, got)
+	}
+}
+
+func TestBurst(t *testing.T) {
+	s, clock := newTestScheduler(Config{Burst: 3})
+
+	if got := elapsed(s, clock, 3); got != 0 {
+		t.Errorf("expected a burst of three to be immediate, took %s", got)
+	}
+
+	if got := elapsed(s, clock, 1); got != MinimumDelay {
+		t.Errorf("expected the request after a burst to wait %s, waited %s", MinimumDelay, got)
+	}
+
+	// the bucket refills while idle
+	clock.Sleep(context.Background(), 3*MinimumDelay)
+
+	if got := elapsed(s, clock, 3); got != 0 {
+		t.Errorf("expected a refilled burst to be immediate, took %s", got)
+	}
+}
+
+func TestBackoffAndDecay(t *testing.T) {
+	s, _ := newTestScheduler(Config{BackoffFactor: 2, DecayFactor: 0.5})
+
+	s.Failure()
+	s.Failure()
+
+	if s.Delay() != 4*MinimumDelay {
+		t.Errorf("expected delay to back off to %s, got %s", 4*MinimumDelay, s.Delay())
+	}
+
+	s.Success()
+
+	if s.Delay() != 2*MinimumDelay {
+		t.Errorf("expected delay to decay to %s, got %s", 2*MinimumDelay, s.Delay())
+	}
+
+	s.Success()
+	s.Success()
+
+	if s.Delay() != MinimumDelay {
+		t.Errorf("expected delay to stop decaying at %s, got %s", MinimumDelay, s.Delay())
+	}
+}
+
+func TestObserveRetryAfter(t *testing.T) {
+	s, clock := newTestScheduler(Config{})
+
+	s.Wait(context.Background())
+	s.Observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"120"}}}, nil)
+
+	if next := s.Next(); !next.Equal(clock.Now().Add(2 * time.Minute)) {
+		t.Errorf("expected requests to pause for 2m, next is at %s", next.Sub(clock.Now()))
+	}
+
+	if s.Delay() <= MinimumDelay {
+		t.Error("expected rate limiting to back off")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	headers := map[string]time.Duration{
+		"30":                            30 * time.Second,
+		"Mon, 01 Jan 2024 00:05:00 GMT": 5 * time.Minute,
+	}
+
+	for header, expected := range headers {
+		if got, err := ParseRetryAfter(header, now); err != nil || got != expected {
+			t.Errorf("%q: expected %s, got %s (%v)", header, expected, got, err)
+		}
+	}
+
+	if _, err := ParseRetryAfter("soon", now); err == nil {
+		t.Error("expected an error for an invalid header")
+	}
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/legowerewolf/AO3fetch/atomicfile"
+)
+
+// region consts
+
+const lockRetryInterval = 50 * time.Millisecond
+const lockTimeout = 5 * time.Second
+const staleLockAge = 30 * time.Second // a lock this old was left by a process that died
+
+var ErrLockTimeout = errors.New("Timed out waiting for shared schedule lock")
+
+// region coordinator
+
+// Coordinator shares request slots for one site between processes, so that
+// several crawls on one machine don't add up to more than one request per
+// interval.
+type Coordinator interface {
+	// Claim takes the shared slot at now if it's free, holding it for
+	// interval. Otherwise it returns how long until the slot is free.
+	Claim(now time.Time, interval time.Duration) (wait time.Duration, err error)
+	// Peek returns when the shared slot is next free.
+	Peek() time.Time
+}
+
+// FileCoordinator is a Coordinator backed by a small state file per host,
+// guarded by a lock file. It works on any OS and filesystem that supports
+// exclusive file creation.
+type FileCoordinator struct {
+	statePath string
+	lock      fileLock
+}
+
+// DefaultStateDir returns the directory shared schedules are kept in.
+func DefaultStateDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "ao3fetch"), nil
+}
+
+// NewFileCoordinator creates a coordinator for requests to host, keeping its
+// state in dir.
+func NewFileCoordinator(dir string, host string) (*FileCoordinator, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	name := stateFileName(host)
+
+	return &FileCoordinator{
+		statePath: filepath.Join(dir, name+".next"),
+		lock:      fileLock{path: filepath.Join(dir, name+".lock"), clock: RealClock},
+	}, nil
+}
+
+func (f *FileCoordinator) Claim(now time.Time, interval time.Duration) (time.Duration, error) {
+	if err := f.lock.acquire(); err != nil {
+		return 0, err
+	}
+	defer f.lock.release()
+
+	if next := f.Peek(); now.Before(next) {
+		return next.Sub(now), nil
+	}
+
+	return 0, f.write(now.Add(interval))
+}
+
+func (f *FileCoordinator) Peek() time.Time {
+	contents, err := os.ReadFile(f.statePath)
+	if err != nil {
+		return time.Time{}
+	}
+
+	next, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(contents)))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return next
+}
+
+func (f *FileCoordinator) write(next time.Time) error {
+	return atomicfile.WriteFile(f.statePath, []byte(next.Format(time.RFC3339Nano)+"\n"))
+}
+
+// region helpers
+
+// fileLock is a lock shared between processes, held by whichever process
+// manages to create its file.
+type fileLock struct {
+	path  string
+	clock Clock
+}
+
+func (l fileLock) acquire() error {
+	deadline := l.clock.Now().Add(lockTimeout)
+
+	for {
+		lockFile, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintln(lockFile, os.Getpid())
+			return lockFile.Close()
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+
+		if info, err := os.Stat(l.path); err == nil && l.clock.Now().Sub(info.ModTime()) > staleLockAge {
+			os.Remove(l.path)
+			continue
+		}
+
+		if l.clock.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+
+		l.clock.Sleep(context.Background(), lockRetryInterval)
+	}
+}
+
+func (l fileLock) release() {
+	os.Remove(l.path)
+}
+
+func stateFileName(host string) string {
+	return strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(strings.ToLower(host))
+}
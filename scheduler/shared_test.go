@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSharedSchedule(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	newShared := func() *Scheduler {
+		coordinator, err := NewFileCoordinator(dir, "archiveofourown.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return New(Config{Clock: clock, Coordinator: coordinator})
+	}
+
+	first, second := newShared(), newShared()
+
+	if got := elapsed(first, clock, 1); got != 0 {
+		t.Errorf("expected the first request to be immediate, took %s", got)
+	}
+
+	// the second process has its own token, but has to wait for the shared slot
+	if next := second.Next(); !next.Equal(clock.Now().Add(MinimumDelay)) {
+		t.Errorf("expected the second process to see the shared slot, next is in %s", next.Sub(clock.Now()))
+	}
+
+	if got := elapsed(second, clock, 1); got != MinimumDelay {
+		t.Errorf("expected the second process to wait %s, waited %s", MinimumDelay, got)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the state file to be left behind, found %d files", len(entries))
+	}
+}
+
+func TestSharedScheduleStaleLock(t *testing.T) {
+	dir := t.TempDir()
+
+	coordinator, err := NewFileCoordinator(dir, "archiveofourown.org:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a lock left behind by a crashed process
+	os.WriteFile(coordinator.lock.path, nil, 0o644)
+	old := time.Now().Add(-2 * staleLockAge)
+	os.Chtimes(coordinator.lock.path, old, old)
+
+	if wait, err := coordinator.Claim(time.Now(), MinimumDelay); err != nil || wait != 0 {
+		t.Errorf("expected to claim the slot past a stale lock, got wait %s, error %v", wait, err)
+	}
+}
@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// region windows
+
+// Window is a daily period, in local time, during which requests may be made.
+// A window whose end is before its start runs past midnight.
+type Window struct {
+	Start time.Duration // since midnight
+	End   time.Duration // since midnight
+}
+
+// ParseWindow reads a window written as "HH:MM-HH:MM".
+func ParseWindow(s string) (w Window, err error) {
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		return w, fmt.Errorf("window %q isn't in the form HH:MM-HH:MM", s)
+	}
+
+	if w.Start, err = parseClock(start); err != nil {
+		return w, err
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return w, err
+	}
+
+	if w.Start == w.End || w.Start == 24*time.Hour {
+		return w, fmt.Errorf("window %q is empty", s)
+	}
+
+	return w, nil
+}
+
+func (w Window) String() string {
+	return formatClock(w.Start) + "-" + formatClock(w.End)
+}
+
+// containing returns when the occurrence of the window around t ends, if t is
+// in one.
+func (w Window) containing(t time.Time) (time.Time, bool) {
+	for _, day := range []int{-1, 0} {
+		start, end := w.occurrence(t, day)
+
+		if !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// nextStart returns when the window next opens after t.
+func (w Window) nextStart(t time.Time) time.Time {
+	for _, day := range []int{0, 1} {
+		if start, _ := w.occurrence(t, day); start.After(t) {
+			return start
+		}
+	}
+
+	start, _ := w.occurrence(t, 2)
+	return start
+}
+
+// occurrence returns the window's start and end on the day offset days from
+// t's.
+func (w Window) occurrence(t time.Time, offset int) (start, end time.Time) {
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d+offset, 0, 0, 0, 0, t.Location())
+
+	start, end = midnight.Add(w.Start), midnight.Add(w.End)
+	if w.End <= w.Start {
+		end = end.Add(24 * time.Hour)
+	}
+
+	return
+}
+
+// Windows is a set of windows. No windows means requests may be made at any
+// time.
+type Windows []Window
+
+// NextOpen returns t if any window is open at t, or else when one next opens.
+func (ws Windows) NextOpen(t time.Time) time.Time {
+	if len(ws) == 0 {
+		return t
+	}
+
+	var next time.Time
+	for _, w := range ws {
+		if _, ok := w.containing(t); ok {
+			return t
+		}
+
+		if start := w.nextStart(t); next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+
+	return next
+}
+
+// closes returns when the windows open at t all close, following on through
+// windows that overlap or touch.
+func (ws Windows) closes(t time.Time) time.Time {
+	end, limit := t, t.Add(48*time.Hour)
+
+	for extended := true; extended && !end.After(limit); {
+		extended = false
+
+		for _, w := range ws {
+			if wEnd, ok := w.containing(end); ok && wEnd.After(end) {
+				end, extended = wEnd, true
+			}
+		}
+	}
+
+	if end.After(limit) {
+		return limit
+	}
+
+	return end
+}
+
+// Finish returns when work that takes the given time would be done if started
+// at start, only progressing while a window is open.
+func (ws Windows) Finish(start time.Time, work time.Duration) time.Time {
+	if len(ws) == 0 {
+		return start.Add(work)
+	}
+
+	t := start
+	for work > 0 {
+		t = ws.NextOpen(t)
+		end := ws.closes(t)
+
+		open := end.Sub(t)
+		if open >= work {
+			return t.Add(work)
+		}
+
+		work -= open
+		t = end
+	}
+
+	return t
+}
+
+// region helpers
+
+func parseClock(s string) (time.Duration, error) {
+	hours, minutes, found := strings.Cut(strings.TrimSpace(s), ":")
+	h, hErr := strconv.Atoi(hours)
+	m, mErr := strconv.Atoi(minutes)
+
+	if !found || hErr != nil || mErr != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%q isn't a time in the form HH:MM", s)
+	}
+
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+}
+
+func mustParseWindows(t *testing.T, raw ...string) (ws Windows) {
+	for _, r := range raw {
+		w, err := ParseWindow(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ws = append(ws, w)
+	}
+
+	return
+}
+
+func TestParseWindow(t *testing.T) {
+	for _, valid := range []string{"01:00-07:00", "22:30-06:00", "00:00-24:00"} {
+		if w, err := ParseWindow(valid); err != nil || w.String() != valid {
+			t.Errorf("%q: got %s, %v", valid, w, err)
+		}
+	}
+
+	for _, invalid := range []string{"01:00", "1-7", "25:00-07:00", "07:00-07:00", "01:60-02:00"} {
+		if _, err := ParseWindow(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestNextOpen(t *testing.T) {
+	ws := mustParseWindows(t, "01:00-07:00", "22:00-23:00")
+
+	cases := map[time.Time]time.Time{
+		at(1, 3, 0):   at(1, 3, 0),  // open
+		at(1, 7, 0):   at(1, 22, 0), // just closed
+		at(1, 23, 30): at(2, 1, 0),  // after the last window of the day
+		at(1, 0, 59):  at(1, 1, 0),  // just before opening
+	}
+
+	for now, expected := range cases {
+		if got := ws.NextOpen(now); !got.Equal(expected) {
+			t.Errorf("at %s: expected %s, got %s", now, expected, got)
+		}
+	}
+
+	overnight := mustParseWindows(t, "22:00-02:00")
+	if got := overnight.NextOpen(at(2, 1, 0)); !got.Equal(at(2, 1, 0)) {
+		t.Errorf("expected an overnight window to be open after midnight, got %s", got)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	ws := mustParseWindows(t, "01:00-07:00")
+
+	// three hours of work from 05:00 runs two hours tonight and one tomorrow
+	if got := ws.Finish(at(1, 5, 0), 3*time.Hour); !got.Equal(at(2, 2, 0)) {
+		t.Errorf("expected to finish at 02:00 tomorrow, got %s", got)
+	}
+
+	// touching windows count as one open period
+	touching := mustParseWindows(t, "01:00-04:00", "04:00-07:00")
+	if got := touching.Finish(at(1, 1, 0), 5*time.Hour); !got.Equal(at(1, 6, 0)) {
+		t.Errorf("expected to finish at 06:00, got %s", got)
+	}
+
+	if got := (Windows{}).Finish(at(1, 5, 0), time.Hour); !got.Equal(at(1, 6, 0)) {
+		t.Errorf("expected no windows to mean no pauses, got %s", got)
+	}
+}
+
+func TestSchedulerWaitsForWindow(t *testing.T) {
+	clock := &fakeClock{now: at(1, 12, 0)}
+	s := New(Config{Clock: clock, Windows: mustParseWindows(t, "01:00-07:00")})
+
+	if open, opens := s.WindowOpen(); open || !opens.Equal(at(2, 1, 0)) {
+		t.Errorf("expected window to be closed until 01:00, got %t, %s", open, opens)
+	}
+
+	s.Wait(context.Background())
+
+	if !clock.Now().Equal(at(2, 1, 0)) {
+		t.Errorf("expected to wait until the window opened, now %s", clock.Now())
+	}
+}
@@ -0,0 +1,234 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// region consts
+
+const dateFromKey = "work_search[date_from]"
+const dateToKey = "work_search[date_to]"
+const queryKey = "work_search[query]"
+
+// bounds used to split partitions that are open-ended on one side
+var earliestDate = time.Date(2008, time.September, 13, 0, 0, 0, 0, time.UTC)
+
+const highestWorkID = 80_000_000
+
+var idRangeMatcher = regexp.MustCompile(`id:\[(\d+|\*) TO (\d+|\*)\]`)
+
+// region partitioning
+
+// Partition splits a works listing into n listings covering contiguous,
+// non-overlapping ranges of revision date ("date") or work ID ("id"). The
+// first and last partitions keep the listing's own bounds, and are open-ended
+// only where the listing is, so that nothing in it is left out and nothing
+// outside it is added.
+func Partition(u url.URL, by string, n int) ([]url.URL, error) {
+	if n < 1 {
+		return nil, errors.New("partition count must be at least 1")
+	}
+
+	if u.Path != "/works" && !strings.HasSuffix(u.Path, "/works") {
+		return nil, fmt.Errorf("only works listings can be partitioned, not %s", u.Path)
+	}
+
+	query := u.Query()
+	query.Del("page")
+
+	switch by {
+	case "date":
+		from, to, err := dateBounds(query)
+		if err != nil {
+			return nil, err
+		}
+
+		return partition(u, query, n, toDay(from), toDay(to), query.Get(dateFromKey), query.Get(dateToKey), setDateRange, dayString), nil
+	case "id":
+		lo, hi, _ := idBounds(query)
+		loStr, hiStr := idRangeEnds(query)
+
+		return partition(u, query, n, lo, hi, loStr, hiStr, setIDRange, idString), nil
+	}
+
+	return nil, fmt.Errorf("unknown partition kind %q; use \"date\" or \"id\"", by)
+}
+
+// Split halves a partition produced by Partition. It returns false if u isn't a
+// partition or its range can't be split any further. ID ranges take precedence
+// over date ranges, since filtered listings always carry (possibly empty) date
+// fields.
+func Split(u url.URL) ([]url.URL, bool) {
+	query := u.Query()
+	query.Del("page")
+
+	if lo, hi, ok := idBounds(query); ok {
+		if hi-lo < 1 {
+			return nil, false
+		}
+
+		mid := lo + (hi-lo)/2
+		loStr, hiStr := idRangeEnds(query)
+
+		return []url.URL{
+			withQuery(u, query, func(q url.Values) { setIDRange(q, loStr, idString(mid)) }),
+			withQuery(u, query, func(q url.Values) { setIDRange(q, idString(mid+1), hiStr) }),
+		}, true
+	}
+
+	if query.Has(dateFromKey) || query.Has(dateToKey) {
+		from, to, err := dateBounds(query)
+		if err != nil {
+			return nil, false
+		}
+
+		lo, hi := toDay(from), toDay(to)
+		if hi-lo < 1 {
+			return nil, false
+		}
+
+		mid := lo + (hi-lo)/2
+
+		return []url.URL{
+			withQuery(u, query, func(q url.Values) { setDateRange(q, q.Get(dateFromKey), dayString(mid)) }),
+			withQuery(u, query, func(q url.Values) { setDateRange(q, dayString(mid+1), q.Get(dateToKey)) }),
+		}, true
+	}
+
+	return nil, false
+}
+
+// region helpers
+
+// partition splits lo to hi into n ranges. The outer ends are given as first and
+// last, as the listing had them, "" being open-ended.
+func partition(u url.URL, query url.Values, n int, lo, hi int64, first, last string, set func(url.Values, string, string), format func(int64) string) []url.URL {
+	count := min(int64(n), max(hi-lo+1, 1))
+	step := max((hi-lo+1)/count, 1)
+
+	var partitions []url.URL
+
+	for i := range count {
+		start, end := first, last
+
+		if i > 0 {
+			start = format(lo + i*step)
+		}
+		if i < count-1 {
+			end = format(lo + (i+1)*step - 1)
+		}
+
+		partitions = append(partitions, withQuery(u, query, func(q url.Values) { set(q, start, end) }))
+	}
+
+	return partitions
+}
+
+func withQuery(u url.URL, query url.Values, modify func(url.Values)) url.URL {
+	q := url.Values{}
+	for key, values := range query {
+		q[key] = append([]string(nil), values...)
+	}
+
+	modify(q)
+	u.RawQuery = q.Encode()
+
+	return u
+}
+
+func dateBounds(query url.Values) (from, to time.Time, err error) {
+	from, to = earliestDate, time.Now().UTC().Truncate(24*time.Hour)
+
+	if s := query.Get(dateFromKey); s != "" {
+		if from, err = time.Parse(time.DateOnly, s); err != nil {
+			return
+		}
+	}
+
+	if s := query.Get(dateToKey); s != "" {
+		if to, err = time.Parse(time.DateOnly, s); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func setDateRange(query url.Values, from, to string) {
+	query.Set(dateFromKey, from)
+	query.Set(dateToKey, to)
+}
+
+func toDay(t time.Time) int64 {
+	return t.Unix() / 86400
+}
+
+func dayString(day int64) string {
+	return time.Unix(day*86400, 0).UTC().Format(time.DateOnly)
+}
+
+func idString(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// idRangeEnds returns the raw ends of the ID range in the query, with "" for
+// an open end.
+func idRangeEnds(query url.Values) (lo, hi string) {
+	match := idRangeMatcher.FindStringSubmatch(query.Get(queryKey))
+	if match == nil {
+		return "", ""
+	}
+
+	lo, hi = match[1], match[2]
+	if lo == "*" {
+		lo = ""
+	}
+	if hi == "*" {
+		hi = ""
+	}
+
+	return
+}
+
+func idBounds(query url.Values) (lo, hi int64, found bool) {
+	lo, hi = 1, highestWorkID
+
+	loStr, hiStr := idRangeEnds(query)
+	found = idRangeMatcher.MatchString(query.Get(queryKey))
+
+	if loStr != "" {
+		lo, _ = strconv.ParseInt(loStr, 10, 64)
+	}
+	if hiStr != "" {
+		hi, _ = strconv.ParseInt(hiStr, 10, 64)
+	}
+
+	return
+}
+
+func setIDRange(query url.Values, lo, hi string) {
+	if lo == "" {
+		lo = "*"
+	}
+	if hi == "" {
+		hi = "*"
+	}
+
+	idRange := fmt.Sprintf("id:[%s TO %s]", lo, hi)
+
+	existing := query.Get(queryKey)
+	switch {
+	case idRangeMatcher.MatchString(existing):
+		query.Set(queryKey, idRangeMatcher.ReplaceAllLiteralString(existing, idRange))
+	case strings.TrimSpace(existing) != "":
+		query.Set(queryKey, fmt.Sprintf("(%s) AND %s", existing, idRange))
+	default:
+		query.Set(queryKey, idRange)
+	}
+}
@@ -0,0 +1,266 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// region consts
+
+const defaultArchive = "https://archiveofourown.org"
+
+// names are matched case-insensitively; short aliases are accepted alongside
+// the names AO3 displays
+
+var ratingIDs = map[string]int{
+	"not rated":             9,
+	"general audiences":     10,
+	"teen and up audiences": 11,
+	"mature":                12,
+	"explicit":              13,
+	"general":               10,
+	"teen":                  11,
+}
+
+var warningIDs = map[string]int{
+	"creator chose not to use archive warnings": 14,
+	"no archive warnings apply":                 16,
+	"graphic depictions of violence":            17,
+	"major character death":                     18,
+	"rape/non-con":                              19,
+	"underage sex":                              20,
+	"underage":                                  20,
+}
+
+var categoryIDs = map[string]int{
+	"f/f":   116,
+	"f/m":   22,
+	"gen":   21,
+	"m/m":   23,
+	"multi": 2246,
+	"other": 24,
+}
+
+var sortColumns = map[string]string{
+	"best match":   "_score",
+	"author":       "authors_to_sort_on",
+	"title":        "title_to_sort_on",
+	"date posted":  "created_at",
+	"date updated": "revised_at",
+	"word count":   "word_count",
+	"hits":         "hits",
+	"kudos":        "kudos_count",
+	"comments":     "comments_count",
+	"bookmarks":    "bookmarks_count",
+}
+
+var crossoverValues = map[string]string{"": "", "include": "", "exclude": "F", "only": "T"}
+var completeValues = map[string]string{"": "", "all": "", "complete": "T", "incomplete": "F"}
+
+var languageMatcher = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]+)*$`)
+
+// region spec
+
+// Spec describes a filtered works listing for a single tag, mirroring the
+// "Sort and Filter" form on AO3's works listings.
+type Spec struct {
+	Archive string `json:"archive"` // base URL of the archive; defaults to AO3
+	Fandom  string `json:"fandom"`  // the tag whose works are listed
+
+	IncludeTags []string `json:"includeTags"`
+	ExcludeTags []string `json:"excludeTags"`
+
+	Ratings           []string `json:"ratings"`
+	ExcludeRatings    []string `json:"excludeRatings"`
+	Warnings          []string `json:"warnings"`
+	ExcludeWarnings   []string `json:"excludeWarnings"`
+	Categories        []string `json:"categories"`
+	ExcludeCategories []string `json:"excludeCategories"`
+
+	Crossovers string `json:"crossovers"` // include, exclude, or only
+	Complete   string `json:"complete"`   // all, complete, or incomplete
+
+	WordsFrom int `json:"wordsFrom"`
+	WordsTo   int `json:"wordsTo"`
+
+	DateFrom string `json:"dateFrom"` // YYYY-MM-DD
+	DateTo   string `json:"dateTo"`   // YYYY-MM-DD
+
+	Language string `json:"language"` // language code, e.g. "en"
+	Query    string `json:"query"`
+
+	SortColumn    string `json:"sortColumn"`
+	SortDirection string `json:"sortDirection"` // asc or desc
+}
+
+// LoadSpec reads a JSON spec file.
+func LoadSpec(filename string) (*Spec, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var spec Spec
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+
+	return &spec, nil
+}
+
+// Validate reports every problem with the spec at once.
+func (s *Spec) Validate() error {
+	var errs []error
+
+	if s.Archive != "" {
+		if u, err := url.Parse(s.Archive); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("archive %q is not an http(s) URL", s.Archive))
+		}
+	}
+
+	if strings.TrimSpace(s.Fandom) == "" {
+		errs = append(errs, errors.New("fandom is required; AO3 only filters works within a tag"))
+	}
+
+	errs = append(errs, checkNames("rating", s.Ratings, ratingIDs)...)
+	errs = append(errs, checkNames("rating", s.ExcludeRatings, ratingIDs)...)
+	errs = append(errs, checkNames("warning", s.Warnings, warningIDs)...)
+	errs = append(errs, checkNames("warning", s.ExcludeWarnings, warningIDs)...)
+	errs = append(errs, checkNames("category", s.Categories, categoryIDs)...)
+	errs = append(errs, checkNames("category", s.ExcludeCategories, categoryIDs)...)
+
+	if _, ok := crossoverValues[strings.ToLower(s.Crossovers)]; !ok {
+		errs = append(errs, fmt.Errorf("crossovers must be include, exclude, or only, not %q", s.Crossovers))
+	}
+
+	if _, ok := completeValues[strings.ToLower(s.Complete)]; !ok {
+		errs = append(errs, fmt.Errorf("complete must be all, complete, or incomplete, not %q", s.Complete))
+	}
+
+	if s.WordsFrom < 0 || s.WordsTo < 0 {
+		errs = append(errs, errors.New("word counts cannot be negative"))
+	} else if s.WordsTo != 0 && s.WordsFrom > s.WordsTo {
+		errs = append(errs, fmt.Errorf("wordsFrom (%d) is greater than wordsTo (%d)", s.WordsFrom, s.WordsTo))
+	}
+
+	from, fromErr := parseDate("dateFrom", s.DateFrom)
+	to, toErr := parseDate("dateTo", s.DateTo)
+	errs = append(errs, fromErr, toErr)
+	if fromErr == nil && toErr == nil && !from.IsZero() && !to.IsZero() && from.After(to) {
+		errs = append(errs, fmt.Errorf("dateFrom (%s) is after dateTo (%s)", s.DateFrom, s.DateTo))
+	}
+
+	if s.Language != "" && !languageMatcher.MatchString(s.Language) {
+		errs = append(errs, fmt.Errorf("language %q is not a language code", s.Language))
+	}
+
+	if _, ok := sortColumns[strings.ToLower(s.SortColumn)]; s.SortColumn != "" && !ok {
+		errs = append(errs, fmt.Errorf("unknown sortColumn %q", s.SortColumn))
+	}
+
+	if direction := strings.ToLower(s.SortDirection); direction != "" && direction != "asc" && direction != "desc" {
+		errs = append(errs, fmt.Errorf("sortDirection must be asc or desc, not %q", s.SortDirection))
+	}
+
+	return errors.Join(errs...)
+}
+
+// URL builds the filtered works listing URL. The spec should be validated
+// first; invalid values are dropped.
+func (s *Spec) URL() *url.URL {
+	archive := s.Archive
+	if archive == "" {
+		archive = defaultArchive
+	}
+
+	base, _ := url.Parse(archive)
+	u := base.JoinPath("/works")
+
+	query := url.Values{}
+	query.Set("commit", "Sort and Filter")
+	query.Set("tag_id", s.Fandom)
+
+	addIDs(query, "include_work_search[rating_ids][]", s.Ratings, ratingIDs)
+	addIDs(query, "exclude_work_search[rating_ids][]", s.ExcludeRatings, ratingIDs)
+	addIDs(query, "include_work_search[archive_warning_ids][]", s.Warnings, warningIDs)
+	addIDs(query, "exclude_work_search[archive_warning_ids][]", s.ExcludeWarnings, warningIDs)
+	addIDs(query, "include_work_search[category_ids][]", s.Categories, categoryIDs)
+	addIDs(query, "exclude_work_search[category_ids][]", s.ExcludeCategories, categoryIDs)
+
+	query.Set("work_search[other_tag_names]", strings.Join(s.IncludeTags, ","))
+	query.Set("work_search[excluded_tag_names]", strings.Join(s.ExcludeTags, ","))
+	query.Set("work_search[crossover]", crossoverValues[strings.ToLower(s.Crossovers)])
+	query.Set("work_search[complete]", completeValues[strings.ToLower(s.Complete)])
+	query.Set("work_search[words_from]", formatCount(s.WordsFrom))
+	query.Set("work_search[words_to]", formatCount(s.WordsTo))
+	query.Set("work_search[date_from]", s.DateFrom)
+	query.Set("work_search[date_to]", s.DateTo)
+	query.Set("work_search[query]", s.Query)
+	query.Set("work_search[language_id]", s.Language)
+	if column, ok := sortColumns[strings.ToLower(s.SortColumn)]; ok {
+		query.Set("work_search[sort_column]", column)
+	} else {
+		query.Set("work_search[sort_column]", "revised_at")
+	}
+
+	if s.SortDirection != "" {
+		query.Set("work_search[sort_direction]", strings.ToLower(s.SortDirection))
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u
+}
+
+// region helpers
+
+func checkNames(kind string, names []string, ids map[string]int) (errs []error) {
+	for _, name := range names {
+		if _, ok := ids[strings.ToLower(strings.TrimSpace(name))]; !ok {
+			errs = append(errs, fmt.Errorf("unknown %s %q", kind, name))
+		}
+	}
+
+	return
+}
+
+func addIDs(query url.Values, key string, names []string, ids map[string]int) {
+	for _, name := range names {
+		if id, ok := ids[strings.ToLower(strings.TrimSpace(name))]; ok {
+			query.Add(key, strconv.Itoa(id))
+		}
+	}
+}
+
+func parseDate(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return t, fmt.Errorf("%s %q is not a YYYY-MM-DD date", field, value)
+	}
+
+	return t, nil
+}
+
+func formatCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(n)
+}
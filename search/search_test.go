@@ -0,0 +1,168 @@
+package search
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidSpecURL(t *testing.T) {
+	spec := Spec{
+		Fandom:      "Star Trek",
+		IncludeTags: []string{"Fluff", "Angst"},
+		ExcludeTags: []string{"Alternate Universe"},
+		Ratings:     []string{"Teen And Up Audiences"},
+		Warnings:    []string{"no archive warnings apply"},
+		Categories:  []string{"M/M", "Gen"},
+		Crossovers:  "exclude",
+		Complete:    "complete",
+		WordsFrom:   1000,
+		WordsTo:     5000,
+		DateFrom:    "2020-01-01",
+		DateTo:      "2020-12-31",
+		Language:    "en",
+		SortColumn:  "Kudos",
+	}
+
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	u := spec.URL()
+	query := u.Query()
+
+	if !strings.HasPrefix(u.String(), "https://archiveofourown.org/works?") {
+		t.Errorf("unexpected listing %s", u)
+	}
+
+	expected := map[string]string{
+		"tag_id":                            "Star Trek",
+		"include_work_search[rating_ids][]": "11",
+		"include_work_search[archive_warning_ids][]": "16",
+		"work_search[other_tag_names]":               "Fluff,Angst",
+		"work_search[excluded_tag_names]":            "Alternate Universe",
+		"work_search[crossover]":                     "F",
+		"work_search[complete]":                      "T",
+		"work_search[words_from]":                    "1000",
+		"work_search[words_to]":                      "5000",
+		"work_search[date_from]":                     "2020-01-01",
+		"work_search[language_id]":                   "en",
+		"work_search[sort_column]":                   "kudos_count",
+	}
+
+	for key, value := range expected {
+		if got := query.Get(key); got != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got)
+		}
+	}
+
+	if categories := query["include_work_search[category_ids][]"]; len(categories) != 2 {
+		t.Errorf("expected 2 categories, got %v", categories)
+	}
+}
+
+func TestInvalidSpec(t *testing.T) {
+	specs := map[string]Spec{
+		"missing fandom":  {},
+		"unknown rating":  {Fandom: "F", Ratings: []string{"PG-13"}},
+		"bad crossovers":  {Fandom: "F", Crossovers: "sometimes"},
+		"inverted words":  {Fandom: "F", WordsFrom: 5000, WordsTo: 1000},
+		"bad date":        {Fandom: "F", DateFrom: "01/02/2020"},
+		"inverted dates":  {Fandom: "F", DateFrom: "2021-01-01", DateTo: "2020-01-01"},
+		"bad sort column": {Fandom: "F", SortColumn: "popularity"},
+		"bad archive":     {Fandom: "F", Archive: "archiveofourown.org"},
+	}
+
+	for name, spec := range specs {
+		if spec.Validate() == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestPartitionByDate(t *testing.T) {
+	u, _ := url.Parse("https://archiveofourown.org/tags/Fluff/works?page=3&work_search[date_from]=2020-01-01&work_search[date_to]=2020-12-31")
+
+	partitions, err := Partition(*u, "date", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(partitions) != 4 {
+		t.Fatalf("expected 4 partitions, got %d", len(partitions))
+	}
+
+	first, last := partitions[0].Query(), partitions[3].Query()
+
+	if first.Get(dateFromKey) != "2020-01-01" || last.Get(dateToKey) != "2020-12-31" {
+		t.Errorf("expected outer partitions to keep the listing's bounds, got %s and %s", first.Get(dateFromKey), last.Get(dateToKey))
+	}
+
+	if first.Has("page") {
+		t.Error("expected page to be dropped")
+	}
+
+	for i := 1; i < len(partitions); i++ {
+		prevEnd, _ := time.Parse(time.DateOnly, partitions[i-1].Query().Get(dateToKey))
+		start, _ := time.Parse(time.DateOnly, partitions[i].Query().Get(dateFromKey))
+
+		if !prevEnd.AddDate(0, 0, 1).Equal(start) {
+			t.Errorf("partitions %d and %d aren't contiguous", i-1, i)
+		}
+	}
+}
+
+func TestPartitionByID(t *testing.T) {
+	for query, expected := range map[string][]string{
+		"id:[100 TO 200]":             {"id:[100 TO 149]", "id:[150 TO 200]"},
+		"id:[100 TO *]":               {"id:[100 TO 40000049]", "id:[40000050 TO *]"},
+		"kudos_count:>10":             {"(kudos_count:>10) AND id:[* TO 40000000]", "(kudos_count:>10) AND id:[40000001 TO *]"},
+		"kudos_count:>10 id:[* TO 9]": {"kudos_count:>10 id:[* TO 4]", "kudos_count:>10 id:[5 TO 9]"},
+	} {
+		u, _ := url.Parse("https://archiveofourown.org/works")
+		u.RawQuery = url.Values{queryKey: {query}}.Encode()
+
+		partitions, err := Partition(*u, "id", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, p := range partitions {
+			got = append(got, p.Query().Get(queryKey))
+		}
+
+		if !slices.Equal(got, expected) {
+			t.Errorf("%s: expected %v, got %v", query, expected, got)
+		}
+	}
+}
+
+func TestSplitByID(t *testing.T) {
+	u, _ := url.Parse("https://archiveofourown.org/tags/Fluff/works?work_search[query]=kudos_count:>10")
+
+	partitions, err := Partition(*u, "id", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	halves, ok := Split(partitions[0])
+	if !ok {
+		t.Fatal("expected partition to be splittable")
+	}
+
+	if got := halves[0].Query().Get(queryKey); got != "(kudos_count:>10) AND id:[* TO 40000000]" {
+		t.Errorf("unexpected first half query %q", got)
+	}
+
+	if got := halves[1].Query().Get(queryKey); got != "(kudos_count:>10) AND id:[40000001 TO *]" {
+		t.Errorf("unexpected second half query %q", got)
+	}
+
+	single, _ := url.Parse("https://archiveofourown.org/works?work_search[query]=id:[5 TO 5]")
+	if _, ok := Split(*single); ok {
+		t.Error("expected single-ID partition not to be splittable")
+	}
+}
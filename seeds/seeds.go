@@ -0,0 +1,174 @@
+package seeds
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of AO3 page a seed URL points to.
+type Kind int
+
+const (
+	Unknown Kind = iota
+	TagWorks
+	TagBookmarks
+	Search
+	UserWorks
+	UserBookmarks
+	UserSeries
+	Gifts
+	PseudWorks
+	PseudBookmarks
+	Series
+	CollectionWorks
+	CollectionBookmarks
+	Bookmarks
+	Work
+	Chapter
+)
+
+var kindNames = map[Kind]string{
+	Unknown:             "unknown page",
+	TagWorks:            "tag works",
+	TagBookmarks:        "tag bookmarks",
+	Search:              "search",
+	UserWorks:           "user works",
+	UserBookmarks:       "user bookmarks",
+	UserSeries:          "user series",
+	Gifts:               "gifts",
+	PseudWorks:          "pseud works",
+	PseudBookmarks:      "pseud bookmarks",
+	Series:              "series",
+	CollectionWorks:     "collection works",
+	CollectionBookmarks: "collection bookmarks",
+	Bookmarks:           "bookmarks",
+	Work:                "work",
+	Chapter:             "chapter",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// IsListing reports whether pages of this kind list work blurbs.
+func (k Kind) IsListing() bool {
+	return k != Unknown && k != Work && k != Chapter
+}
+
+// Seed is a classified and normalized seed URL.
+type Seed struct {
+	URL      url.URL
+	Kind     Kind
+	Warnings []string
+}
+
+// Classify works out what kind of page a seed URL points to. Pages that don't
+// list works themselves, but have an obvious works listing (a tag's landing
+// page, a user's profile, a collection's dashboard), are converted to that
+// listing with a warning. Chapters and a work's chapter index are converted to
+// the work's canonical URL. Pages that can't be crawled for works at all are rejected.
+func Classify(u url.URL) (s Seed, err error) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return s, fmt.Errorf("%s is not an http(s) URL", u.String())
+	}
+
+	if u.Host == "" {
+		return s, fmt.Errorf("%s has no host", u.String())
+	}
+
+	u.Fragment = ""
+	s.URL = u
+
+	p := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch {
+	case match(p, "tags", "*", "works"):
+		s.Kind = TagWorks
+	case match(p, "tags", "*", "bookmarks"):
+		s.Kind = TagBookmarks
+	case match(p, "tags", "*"):
+		s.convert(TagWorks, "tag pages don't list works", "tags", p[1], "works")
+	case match(p, "works"), match(p, "works", "search"):
+		s.Kind = Search
+	case match(p, "works", "#"), match(p, "works", "#", "navigate"):
+		s.canonicalWork(Work, p[1])
+	case match(p, "works", "#", "chapters", "#"):
+		s.canonicalWork(Chapter, p[1])
+	case match(p, "users", "*", "works"):
+		s.Kind = UserWorks
+	case match(p, "users", "*", "bookmarks"):
+		s.Kind = UserBookmarks
+	case match(p, "users", "*", "series"), match(p, "users", "*", "pseuds", "*", "series"):
+		s.Kind = UserSeries
+		s.Warnings = append(s.Warnings, "series listings don't list works directly; works are only found by crawling each series")
+	case match(p, "users", "*", "gifts"):
+		s.Kind = Gifts
+	case match(p, "users", "*"), match(p, "users", "*", "profile"):
+		s.convert(UserWorks, "user pages don't list all of a user's works", "users", p[1], "works")
+	case match(p, "users", "*", "pseuds", "*", "works"):
+		s.Kind = PseudWorks
+	case match(p, "users", "*", "pseuds", "*", "bookmarks"):
+		s.Kind = PseudBookmarks
+	case match(p, "users", "*", "pseuds", "*"):
+		s.convert(PseudWorks, "pseud pages don't list all of a pseud's works", "users", p[1], "pseuds", p[3], "works")
+	case match(p, "series", "#"):
+		s.Kind = Series
+	case match(p, "collections", "*", "works"):
+		s.Kind = CollectionWorks
+	case match(p, "collections", "*", "bookmarks"):
+		s.Kind = CollectionBookmarks
+	case match(p, "collections", "*"):
+		s.convert(CollectionWorks, "collection pages don't list all of a collection's works", "collections", p[1], "works")
+	case match(p, "bookmarks"):
+		s.Kind = Bookmarks
+	default:
+		return s, errors.New(u.Path + " isn't a page AO3Fetch can collect works from")
+	}
+
+	return s, nil
+}
+
+// convert points the seed at a different page on the same host, dropping the
+// query, and warns about the change.
+func (s *Seed) convert(kind Kind, reason string, path ...string) {
+	converted := url.URL{Scheme: s.URL.Scheme, Host: s.URL.Host, Path: "/" + strings.Join(path, "/")}
+
+	s.Warnings = append(s.Warnings, fmt.Sprintf("%s; using %s instead", reason, converted.String()))
+	s.Kind = kind
+	s.URL = converted
+}
+
+func (s *Seed) canonicalWork(kind Kind, id string) {
+	s.Kind = kind
+	s.URL = url.URL{Scheme: s.URL.Scheme, Host: s.URL.Host, Path: "/works/" + id}
+}
+
+// match reports whether path segments fit a pattern, where "*" matches any
+// segment and "#" matches a numeric ID.
+func match(segments []string, pattern ...string) bool {
+	if len(segments) != len(pattern) {
+		return false
+	}
+
+	for i, want := range pattern {
+		switch want {
+		case "*":
+			if segments[i] == "" {
+				return false
+			}
+		case "#":
+			if _, err := strconv.Atoi(segments[i]); err != nil {
+				return false
+			}
+		default:
+			if segments[i] != want {
+				return false
+			}
+		}
+	}
+
+	return true
+}
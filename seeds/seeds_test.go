@@ -0,0 +1,61 @@
+package seeds
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		raw      string
+		kind     Kind
+		url      string
+		warnings int
+	}{
+		{"https://archiveofourown.org/tags/Fluff/works?page=2", TagWorks, "https://archiveofourown.org/tags/Fluff/works?page=2", 0},
+		{"https://archiveofourown.org/tags/Fluff", TagWorks, "https://archiveofourown.org/tags/Fluff/works", 1},
+		{"https://archiveofourown.org/works?tag_id=Fluff&work_search[complete]=T", Search, "https://archiveofourown.org/works?tag_id=Fluff&work_search[complete]=T", 0},
+		{"https://archiveofourown.org/users/someone/bookmarks", UserBookmarks, "https://archiveofourown.org/users/someone/bookmarks", 0},
+		{"https://archiveofourown.org/users/someone/profile", UserWorks, "https://archiveofourown.org/users/someone/works", 1},
+		{"https://archiveofourown.org/users/someone/pseuds/other", PseudWorks, "https://archiveofourown.org/users/someone/pseuds/other/works", 1},
+		{"https://archiveofourown.org/series/123", Series, "https://archiveofourown.org/series/123", 0},
+		{"https://archiveofourown.org/collections/thing", CollectionWorks, "https://archiveofourown.org/collections/thing/works", 1},
+		{"https://archiveofourown.org/works/456?view_adult=true#main", Work, "https://archiveofourown.org/works/456", 0},
+		{"https://archiveofourown.org/works/456/chapters/789", Chapter, "https://archiveofourown.org/works/456", 0},
+		{"https://archiveofourown.org/works/456/navigate", Work, "https://archiveofourown.org/works/456", 0},
+		{"https://example.com/tags/Fluff/works", TagWorks, "https://example.com/tags/Fluff/works", 0},
+	}
+
+	for _, c := range cases {
+		u, _ := url.Parse(c.raw)
+
+		seed, err := Classify(*u)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.raw, err)
+			continue
+		}
+
+		if seed.Kind != c.kind || seed.URL.String() != c.url || len(seed.Warnings) != c.warnings {
+			t.Errorf("%s: got %s %s %v", c.raw, seed.Kind, seed.URL.String(), seed.Warnings)
+		}
+	}
+}
+
+func TestClassifyRejects(t *testing.T) {
+	for _, raw := range []string{
+		"ftp://archiveofourown.org/tags/Fluff/works",
+		"/tags/Fluff/works",
+		"https://archiveofourown.org/comments/5",
+		"https://archiveofourown.org/works/456/bookmarks",
+		"https://archiveofourown.org/works/456/kudos",
+		"https://archiveofourown.org/works/456/comments",
+		"https://archiveofourown.org/works/456/chapters",
+		"https://archiveofourown.org/",
+	} {
+		u, _ := url.Parse(raw)
+
+		if _, err := Classify(*u); err == nil {
+			t.Errorf("%s: expected rejection", raw)
+		}
+	}
+}